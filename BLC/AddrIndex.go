@@ -0,0 +1,357 @@
+package BLC
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+// 地址索引管理文件
+// 索引主链上与每个地址相关的交易(转入该地址或花费该地址的输出)，
+// 在更新最新区块的数据库事务中随区块的接入与断开同步更新
+// 存储结构(表addrindex)：
+//   tip -- 索引对应的最新区块哈希
+//   子表 "a"+地址：
+//     "h"+高度(8字节)+交易序号(4字节) -> 索引项(gob)，按键排序即交易在主链上的顺序
+//     "t"+交易哈希 -> 该交易索引项的键，用于查找被花费的输出的金额
+// 早期版本创建的数据库没有地址索引，首次查询时从创世区块开始建立
+
+// 表名称
+const addrIndexTableName = "addrindex"
+
+// 索引对应的最新区块哈希
+var addrIndexTipKey = []byte("tip")
+
+// 地址索引项
+type AddressIndexEntry struct {
+	TxHash    []byte
+	BlockHash []byte
+	Height    int64
+	TimeStamp int64
+	TxIndex   int    // 交易在区块中的序号
+	Received  Amount // 该交易中转入该地址的金额
+	Sent      Amount // 该交易花费的该地址的金额
+}
+
+// 该交易引起的余额变化，花费多于转入时为负数
+func (entry *AddressIndexEntry) Delta() Amount {
+	return entry.Received - entry.Sent
+}
+
+func addressBucketName(address string) []byte {
+	return append([]byte("a"), address...)
+}
+
+func addressEntryKey(height int64, txIndex int) []byte {
+	key := make([]byte, 13)
+	key[0] = 'h'
+	binary.BigEndian.PutUint64(key[1:9], uint64(height))
+	binary.BigEndian.PutUint32(key[9:], uint32(txIndex))
+	return key
+}
+
+func addressTxKey(txHash []byte) []byte {
+	return append([]byte("t"), txHash...)
+}
+
+func encodeAddressIndexEntry(entry *AddressIndexEntry) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(entry); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func decodeAddressIndexEntry(data []byte) (*AddressIndexEntry, error) {
+	var entry AddressIndexEntry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// 数据库事务中读取区块，同一事务中读取过的区块被缓存
+type txBlockReader struct {
+	bucket *bolt.Bucket
+	cache  map[string]*Block
+}
+
+func (reader *txBlockReader) get(hash []byte) (*Block, error) {
+	if block, ok := reader.cache[string(hash)]; ok {
+		return block, nil
+	}
+	blockBytes := reader.bucket.Get(hash)
+	if blockBytes == nil {
+		return nil, fmt.Errorf("block [%x] not found", hash)
+	}
+	block, err := DecodeBlock(blockBytes)
+	if err != nil {
+		return nil, err
+	}
+	reader.cache[string(hash)] = block
+	return block, nil
+}
+
+// 父区块，创世区块返回nil
+func (reader *txBlockReader) parent(block *Block) (*Block, error) {
+	if isNullHash(block.PrevBlockHash) {
+		return nil, nil
+	}
+	return reader.get(block.PrevBlockHash)
+}
+
+// 将地址索引更新到newTip：断开索引中不在新主链上的区块，再按高度从低到高接入新主链上的区块
+// 在更新最新区块的数据库事务中调用；地址索引不存在时不做处理
+func updateAddressIndex(tx *bolt.Tx, newTip []byte) error {
+	index := tx.Bucket([]byte(addrIndexTableName))
+	if index == nil {
+		return nil
+	}
+	b := tx.Bucket([]byte(blockTableName))
+	if b == nil {
+		return errors.New("block bucket not found")
+	}
+	reader := &txBlockReader{bucket: b, cache: make(map[string]*Block)}
+
+	var oldBlock *Block
+	if oldTip := index.Get(addrIndexTipKey); len(oldTip) > 0 {
+		var err error
+		if oldBlock, err = reader.get(oldTip); err != nil {
+			// 索引对应的区块已不存在，重新建立索引
+			chainLog.Warnf("地址索引的最新区块 [%x] 不存在，重新建立地址索引: %v\n", oldTip, err)
+			if index, err = resetAddressIndex(tx); err != nil {
+				return err
+			}
+			oldBlock = nil
+		}
+	}
+	newBlock, err := reader.get(newTip)
+	if err != nil {
+		return err
+	}
+
+	var disconnected, connected []*Block
+	for oldBlock != nil && newBlock != nil && !bytes.Equal(oldBlock.Hash, newBlock.Hash) {
+		if oldBlock.Height >= newBlock.Height {
+			disconnected = append(disconnected, oldBlock)
+			oldBlock, err = reader.parent(oldBlock)
+		} else {
+			connected = append(connected, newBlock)
+			newBlock, err = reader.parent(newBlock)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// 空索引从创世区块开始接入
+	for oldBlock == nil && newBlock != nil {
+		connected = append(connected, newBlock)
+		if newBlock, err = reader.parent(newBlock); err != nil {
+			return err
+		}
+	}
+
+	for _, block := range disconnected {
+		if err := disconnectAddressIndex(index, block); err != nil {
+			return err
+		}
+	}
+	for i := len(connected) - 1; i >= 0; i-- {
+		if err := connectAddressIndex(index, reader, connected[i]); err != nil {
+			return err
+		}
+	}
+	if len(disconnected) > 0 || len(connected) > 1 {
+		chainLog.Debugf("地址索引更新到区块 [%x]：断开 [%d] 个区块，接入 [%d] 个区块\n", newTip, len(disconnected), len(connected))
+	}
+	return index.Put(addrIndexTipKey, newTip)
+}
+
+// 删除并重新创建地址索引表
+func resetAddressIndex(tx *bolt.Tx) (*bolt.Bucket, error) {
+	if err := tx.DeleteBucket([]byte(addrIndexTableName)); err != nil && err != bolt.ErrBucketNotFound {
+		return nil, err
+	}
+	return tx.CreateBucket([]byte(addrIndexTableName))
+}
+
+// 接入区块：为区块中每笔交易涉及的地址添加索引项
+func connectAddressIndex(index *bolt.Bucket, reader *txBlockReader, block *Block) error {
+	for txIndex, transaction := range block.Txs {
+		entries := make(map[string]*AddressIndexEntry)
+		entry := func(address string) *AddressIndexEntry {
+			if entries[address] == nil {
+				entries[address] = &AddressIndexEntry{
+					TxHash:    transaction.TxHash,
+					BlockHash: block.Hash,
+					Height:    block.Height,
+					TimeStamp: block.TimeStamp,
+					TxIndex:   txIndex,
+				}
+			}
+			return entries[address]
+		}
+		if !transaction.IsCoinbaseTransaction() {
+			for _, in := range transaction.Vins {
+				value, err := spentOutputValue(index, reader, in)
+				if err != nil {
+					return fmt.Errorf("transaction [%x]: %v", transaction.TxHash, err)
+				}
+				e := entry(in.ScriptSig)
+				if e.Sent, err = e.Sent.Add(value); err != nil {
+					return err
+				}
+			}
+		}
+		for _, out := range transaction.Vouts {
+			if out.IsDataOutput() || out.ScriptPubkey == "" {
+				continue
+			}
+			e := entry(out.ScriptPubkey)
+			var err error
+			if e.Received, err = e.Received.Add(out.Value); err != nil {
+				return err
+			}
+		}
+
+		key := addressEntryKey(block.Height, txIndex)
+		for address, e := range entries {
+			b, err := index.CreateBucketIfNotExists(addressBucketName(address))
+			if err != nil {
+				return err
+			}
+			data, err := encodeAddressIndexEntry(e)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key, data); err != nil {
+				return err
+			}
+			if err := b.Put(addressTxKey(transaction.TxHash), key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 被花费的输出的金额：该输出属于花费它的地址，由该地址的索引找到输出所在的区块
+func spentOutputValue(index *bolt.Bucket, reader *txBlockReader, in *TxInput) (Amount, error) {
+	notFound := fmt.Errorf("spent output [%x:%d] not found in address index", in.TxHash, in.Vout)
+	b := index.Bucket(addressBucketName(in.ScriptSig))
+	if b == nil {
+		return 0, notFound
+	}
+	key := b.Get(addressTxKey(in.TxHash))
+	if key == nil {
+		return 0, notFound
+	}
+	entry, err := decodeAddressIndexEntry(b.Get(key))
+	if err != nil {
+		return 0, err
+	}
+	block, err := reader.get(entry.BlockHash)
+	if err != nil {
+		return 0, err
+	}
+	if entry.TxIndex >= len(block.Txs) || !bytes.Equal(block.Txs[entry.TxIndex].TxHash, in.TxHash) {
+		return 0, notFound
+	}
+	vouts := block.Txs[entry.TxIndex].Vouts
+	if in.Vout < 0 || in.Vout >= len(vouts) {
+		return 0, notFound
+	}
+	return vouts[in.Vout].Value, nil
+}
+
+// 断开区块：删除区块中每笔交易涉及的地址的索引项
+func disconnectAddressIndex(index *bolt.Bucket, block *Block) error {
+	for _, transaction := range block.Txs {
+		var addresses []string
+		if !transaction.IsCoinbaseTransaction() {
+			for _, in := range transaction.Vins {
+				addresses = append(addresses, in.ScriptSig)
+			}
+		}
+		for _, out := range transaction.Vouts {
+			addresses = append(addresses, out.ScriptPubkey)
+		}
+		txKey := addressTxKey(transaction.TxHash)
+		for _, address := range addresses {
+			b := index.Bucket(addressBucketName(address))
+			if b == nil {
+				continue
+			}
+			key := b.Get(txKey)
+			if key == nil {
+				continue
+			}
+			if err := b.Delete(append([]byte{}, key...)); err != nil {
+				return err
+			}
+			if err := b.Delete(txKey); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 查询主链上与指定地址相关的交易的索引项，按交易在主链上的顺序排列
+// 地址索引不存在或落后于最新区块时先建立或更新索引
+func (blockchain *BlockChain) AddressIndex(address string) ([]*AddressIndexEntry, error) {
+	if blockchain.IsEmpty() {
+		return nil, nil
+	}
+	var upToDate bool
+	err := blockchain.DB.View(func(tx *bolt.Tx) error {
+		if index := tx.Bucket([]byte(addrIndexTableName)); index != nil {
+			upToDate = bytes.Equal(index.Get(addrIndexTipKey), blockchain.Tip)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !upToDate {
+		err = blockchain.DB.Update(func(tx *bolt.Tx) error {
+			if _, err := tx.CreateBucketIfNotExists([]byte(addrIndexTableName)); err != nil {
+				return err
+			}
+			return updateAddressIndex(tx, blockchain.Tip)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("update the address index failed: %v", err)
+		}
+	}
+
+	var entries []*AddressIndexEntry
+	err = blockchain.DB.View(func(tx *bolt.Tx) error {
+		index := tx.Bucket([]byte(addrIndexTableName))
+		if index == nil {
+			return errors.New("address index bucket not found")
+		}
+		b := index.Bucket(addressBucketName(address))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		prefix := []byte("h")
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			entry, err := decodeAddressIndexEntry(v)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,162 @@
+package BLC
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+var testAddresses = []string{"alice", "bob", "carol", "dave"}
+
+// 地址索引中address子表的键数量
+func addressIndexKeyCount(t *testing.T, blockchain *BlockChain, address string) int {
+	t.Helper()
+	var count int
+	err := blockchain.DB.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(addrIndexTableName)).Bucket(addressBucketName(address)); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				count++
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// 所有测试地址的索引项
+func testAddressIndex(t *testing.T, blockchain *BlockChain) map[string][]*AddressIndexEntry {
+	t.Helper()
+	index := make(map[string][]*AddressIndexEntry)
+	for _, address := range testAddresses {
+		entries, err := blockchain.AddressIndex(address)
+		if err != nil {
+			t.Fatalf("AddressIndex(%s) error: %v", address, err)
+		}
+		// 每个索引项对应一个"h"键和一个"t"键，断开区块后不应残留
+		if count := addressIndexKeyCount(t, blockchain, address); count != 2*len(entries) {
+			t.Fatalf("address index of [%s] has %d keys for %d entries", address, count, len(entries))
+		}
+		index[address] = entries
+	}
+	return index
+}
+
+// 检查地址索引：各地址的余额变化与期望一致，且所有索引项都在主链上
+func checkAddressIndex(t *testing.T, blockchain *BlockChain, want map[string][]Amount) map[string][]*AddressIndexEntry {
+	t.Helper()
+	index := testAddressIndex(t, blockchain)
+	for _, address := range testAddresses {
+		var deltas []Amount
+		for _, entry := range index[address] {
+			deltas = append(deltas, entry.Delta())
+			block := blockchain.GetBlockByHeight(entry.Height)
+			if block == nil || !bytes.Equal(block.Hash, entry.BlockHash) {
+				t.Fatalf("entry of [%s] at height %d is not on the main chain", address, entry.Height)
+			}
+			if !bytes.Equal(block.Txs[entry.TxIndex].TxHash, entry.TxHash) {
+				t.Fatalf("entry of [%s] at height %d has a wrong tx index", address, entry.Height)
+			}
+		}
+		if !reflect.DeepEqual(deltas, want[address]) {
+			t.Errorf("deltas of [%s] = %v, want %v", address, deltas, want[address])
+		}
+	}
+	return index
+}
+
+// 删除地址索引后重新建立，结果应与增量更新的索引相同
+func checkAddressIndexRebuild(t *testing.T, blockchain *BlockChain, index map[string][]*AddressIndexEntry) {
+	t.Helper()
+	err := blockchain.DB.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte(addrIndexTableName))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt := testAddressIndex(t, blockchain); !reflect.DeepEqual(rebuilt, index) {
+		t.Fatal("rebuilt address index differs from the updated one")
+	}
+}
+
+func TestAddressIndexReorg(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+	genesis := blockchain.TipBlock()
+
+	// 主链：alice -> bob 3，bob -> carol 1
+	a2 := newTestBlock(t, blockchain, genesis, "alice", "bob", 3*CoinUnit)
+	acceptTestBlock(t, blockchain, a2)
+	a3 := newTestBlock(t, blockchain, a2, "bob", "carol", CoinUnit)
+	acceptTestBlock(t, blockchain, a3)
+	mainIndex := checkAddressIndex(t, blockchain, map[string][]Amount{
+		"alice": {10 * CoinUnit, -3 * CoinUnit},
+		"bob":   {3 * CoinUnit, -CoinUnit},
+		"carol": {CoinUnit},
+	})
+
+	// 分叉链：alice -> carol 4，alice -> dave 2，carol -> bob 1，更长的分叉链成为主链
+	b2 := newTestBlock(t, blockchain, genesis, "alice", "carol", 4*CoinUnit)
+	if acceptTestBlock(t, blockchain, b2) {
+		t.Fatal("side chain became the main chain before it was longer")
+	}
+	b3 := newTestBlock(t, blockchain, b2, "alice", "dave", 2*CoinUnit)
+	if acceptTestBlock(t, blockchain, b3) {
+		t.Fatal("side chain became the main chain before it was longer")
+	}
+	checkAddressIndex(t, blockchain, map[string][]Amount{
+		"alice": {10 * CoinUnit, -3 * CoinUnit},
+		"bob":   {3 * CoinUnit, -CoinUnit},
+		"carol": {CoinUnit},
+	})
+	b4 := newTestBlock(t, blockchain, b3, "carol", "bob", CoinUnit)
+	if !acceptTestBlock(t, blockchain, b4) {
+		t.Fatal("longer side chain did not become the main chain")
+	}
+	sideIndex := checkAddressIndex(t, blockchain, map[string][]Amount{
+		"alice": {10 * CoinUnit, -4 * CoinUnit, -2 * CoinUnit},
+		"bob":   {CoinUnit},
+		"carol": {4 * CoinUnit, -CoinUnit},
+		"dave":  {2 * CoinUnit},
+	})
+	checkAddressIndexRebuild(t, blockchain, sideIndex)
+
+	// 原主链再次变长，切换回原主链
+	a4 := newTestBlock(t, blockchain, a3, "carol", "dave", CoinUnit/2)
+	acceptTestBlock(t, blockchain, a4)
+	a5 := newTestBlock(t, blockchain, a4, "alice", "bob", CoinUnit)
+	if !acceptTestBlock(t, blockchain, a5) {
+		t.Fatal("original chain did not become the main chain again")
+	}
+	index := checkAddressIndex(t, blockchain, map[string][]Amount{
+		"alice": {10 * CoinUnit, -3 * CoinUnit, -CoinUnit},
+		"bob":   {3 * CoinUnit, -CoinUnit, CoinUnit},
+		"carol": {CoinUnit, -CoinUnit / 2},
+		"dave":  {CoinUnit / 2},
+	})
+	if !reflect.DeepEqual(index["bob"][:2], mainIndex["bob"]) {
+		t.Fatal("entries of the original chain changed after switching back")
+	}
+	checkAddressIndexRebuild(t, blockchain, index)
+}
+
+// 地址索引的最新区块不存在时重新建立索引
+func TestAddressIndexMissingTip(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+	acceptTestBlock(t, blockchain, newTestBlock(t, blockchain, blockchain.TipBlock(), "alice", "bob", CoinUnit))
+	want := testAddressIndex(t, blockchain)
+
+	err := blockchain.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(addrIndexTableName)).Put(addrIndexTipKey, []byte("missing"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := testAddressIndex(t, blockchain); !reflect.DeepEqual(got, want) {
+		t.Fatal("address index differs after rebuilding from a missing tip")
+	}
+}
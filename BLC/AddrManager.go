@@ -0,0 +1,229 @@
+package BLC
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// 节点地址管理文件
+// 记录已知节点的地址及连接情况，保存在数据库的peers表中，节点重启后优先重新连接可用的节点
+
+// 节点地址表名称
+const peerTableName = "peers"
+
+const (
+	// 最多保存的地址数量
+	maxKnownAddresses = 2000
+	// 连续失败达到该次数且长时间未成功连接的地址不再使用
+	maxAddressFailures = 10
+	// 超过该时间未成功连接的地址视为过期
+	addressExpiry = 7 * 24 * time.Hour
+	// 连接失败后的重试间隔(随失败次数加倍)及其上限
+	addressRetryInterval    = 30 * time.Second
+	maxAddressRetryInterval = time.Hour
+)
+
+// 已知节点地址
+type KnownAddress struct {
+	Addr        string // 节点的监听地址
+	Source      string // 从哪个节点得知该地址
+	LastSeen    int64  // 最近一次得知该节点在线的时间
+	LastAttempt int64  // 最近一次尝试连接的时间
+	LastSuccess int64  // 最近一次成功连接的时间
+	Failures    int    // 连续连接失败的次数
+}
+
+// 是否为不可用的地址
+func (ka *KnownAddress) isBad(now time.Time) bool {
+	return ka.Failures >= maxAddressFailures && now.Sub(time.Unix(ka.LastSuccess, 0)) > addressExpiry
+}
+
+// 当前是否可以尝试连接：连接失败后按失败次数等待
+func (ka *KnownAddress) retryable(now time.Time) bool {
+	if ka.Failures == 0 {
+		return true
+	}
+	interval := addressRetryInterval << uint(ka.Failures-1)
+	if interval > maxAddressRetryInterval || interval <= 0 {
+		interval = maxAddressRetryInterval
+	}
+	return now.Sub(time.Unix(ka.LastAttempt, 0)) >= interval
+}
+
+// 地址管理器
+type AddrManager struct {
+	db        *bolt.DB
+	mutex     sync.Mutex
+	addresses map[string]*KnownAddress
+}
+
+// 创建地址管理器，从数据库中加载已保存的地址
+func NewAddrManager(db *bolt.DB) (*AddrManager, error) {
+	manager := &AddrManager{db: db, addresses: make(map[string]*KnownAddress)}
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(peerTableName))
+		if err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var ka KnownAddress
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&ka); err != nil {
+				return fmt.Errorf("decode address [%s] failed: %v", k, err)
+			}
+			manager.addresses[ka.Addr] = &ka
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+// 保存地址
+func (manager *AddrManager) save(ka *KnownAddress) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(ka); err != nil {
+		return
+	}
+	manager.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(peerTableName)).Put([]byte(ka.Addr), buffer.Bytes())
+	})
+}
+
+// 删除地址
+func (manager *AddrManager) delete(addr string) {
+	delete(manager.addresses, addr)
+	manager.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(peerTableName)).Delete([]byte(addr))
+	})
+}
+
+// 添加地址，已存在时只更新最近在线时间
+func (manager *AddrManager) AddAddress(addr, source string, lastSeen time.Time) {
+	if addr == "" {
+		return
+	}
+	now := time.Now()
+	if lastSeen.After(now) {
+		lastSeen = now
+	}
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	ka, ok := manager.addresses[addr]
+	if !ok {
+		if len(manager.addresses) >= maxKnownAddresses {
+			return
+		}
+		ka = &KnownAddress{Addr: addr, Source: source}
+		manager.addresses[addr] = ka
+	} else if lastSeen.Unix() <= ka.LastSeen {
+		return
+	}
+	ka.LastSeen = lastSeen.Unix()
+	manager.save(ka)
+}
+
+// 记录一次连接尝试
+func (manager *AddrManager) Attempt(addr string) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	if ka, ok := manager.addresses[addr]; ok {
+		ka.LastAttempt = time.Now().Unix()
+		manager.save(ka)
+	}
+}
+
+// 连接成功
+func (manager *AddrManager) Good(addr string) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	ka, ok := manager.addresses[addr]
+	if !ok {
+		ka = &KnownAddress{Addr: addr}
+		manager.addresses[addr] = ka
+	}
+	now := time.Now().Unix()
+	ka.LastSeen = now
+	ka.LastSuccess = now
+	ka.Failures = 0
+	manager.save(ka)
+}
+
+// 连接失败，不可用的地址直接删除
+func (manager *AddrManager) Failed(addr string) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	ka, ok := manager.addresses[addr]
+	if !ok {
+		return
+	}
+	ka.Failures++
+	if ka.isBad(time.Now()) {
+		manager.delete(addr)
+		return
+	}
+	manager.save(ka)
+}
+
+// 地址数量
+func (manager *AddrManager) Count() int {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	return len(manager.addresses)
+}
+
+// 按可用程度排序的地址：失败次数少的优先，其次是最近成功连接的，最后是最近在线的
+func (manager *AddrManager) sorted(now time.Time) []*KnownAddress {
+	var list []*KnownAddress
+	for _, ka := range manager.addresses {
+		if !ka.isBad(now) {
+			copied := *ka
+			list = append(list, &copied)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Failures != list[j].Failures {
+			return list[i].Failures < list[j].Failures
+		}
+		if list[i].LastSuccess != list[j].LastSuccess {
+			return list[i].LastSuccess > list[j].LastSuccess
+		}
+		if list[i].LastSeen != list[j].LastSeen {
+			return list[i].LastSeen > list[j].LastSeen
+		}
+		return list[i].Addr < list[j].Addr
+	})
+	return list
+}
+
+// 所有地址，按可用程度排序
+func (manager *AddrManager) Addresses() []*KnownAddress {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	return manager.sorted(time.Now())
+}
+
+// 选择最多count个可以尝试连接的地址，跳过exclude中的地址
+func (manager *AddrManager) Candidates(count int, exclude map[string]bool) []string {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	now := time.Now()
+	var candidates []string
+	for _, ka := range manager.sorted(now) {
+		if len(candidates) >= count {
+			break
+		}
+		if exclude[ka.Addr] || !ka.retryable(now) {
+			continue
+		}
+		candidates = append(candidates, ka.Addr)
+	}
+	return candidates
+}
@@ -0,0 +1,145 @@
+package BLC
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 金额管理文件
+
+// 金额，以最小单位表示(1个币 = 10^8个最小单位)
+type Amount int64
+
+const (
+	// 小数位数
+	amountDecimals = 8
+	// 1个币对应的最小单位数量
+	CoinUnit Amount = 100000000
+	// 金额上限
+	MaxAmount Amount = 21000000 * CoinUnit
+)
+
+var (
+	ErrAmountOverflow    = errors.New("amount out of range")
+	ErrAmountNotPositive = errors.New("amount must be positive")
+)
+
+// 解析十进制金额字符串，例如"1.25"、"3"、"0.00000001"
+// 拒绝负数、科学计数法、超过8位的小数以及超出上限的金额
+func ParseAmount(str string) (Amount, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return 0, errors.New("empty amount")
+	}
+	if strings.HasPrefix(str, "-") {
+		return 0, fmt.Errorf("amount [%s] is negative", str)
+	}
+	intPart, fracPart := str, ""
+	if index := strings.IndexByte(str, '.'); index >= 0 {
+		intPart, fracPart = str[:index], str[index+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return 0, fmt.Errorf("malformed amount [%s]", str)
+	}
+	if len(fracPart) > amountDecimals {
+		return 0, fmt.Errorf("amount [%s] has more than %d decimals", str, amountDecimals)
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return 0, fmt.Errorf("malformed amount [%s]", str)
+			}
+		}
+	}
+
+	var coins, units int64
+	var err error
+	if intPart != "" {
+		// 整数部分超过上限时直接拒绝，避免乘法溢出
+		if len(intPart) > 10 {
+			return 0, fmt.Errorf("amount [%s]: %v", str, ErrAmountOverflow)
+		}
+		if coins, err = strconv.ParseInt(intPart, 10, 64); err != nil {
+			return 0, fmt.Errorf("malformed amount [%s]", str)
+		}
+	}
+	if fracPart != "" {
+		fracPart += strings.Repeat("0", amountDecimals-len(fracPart))
+		if units, err = strconv.ParseInt(fracPart, 10, 64); err != nil {
+			return 0, fmt.Errorf("malformed amount [%s]", str)
+		}
+	}
+	amount := Amount(coins)*CoinUnit + Amount(units)
+	if amount > MaxAmount {
+		return 0, fmt.Errorf("amount [%s]: %v", str, ErrAmountOverflow)
+	}
+	return amount, nil
+}
+
+// 解析转账金额，必须大于0
+func ParsePositiveAmount(str string) (Amount, error) {
+	amount, err := ParseAmount(str)
+	if err != nil {
+		return 0, err
+	}
+	if amount <= 0 {
+		return 0, fmt.Errorf("amount [%s]: %v", str, ErrAmountNotPositive)
+	}
+	return amount, nil
+}
+
+// 金额是否在合法范围内[0, MaxAmount]
+func (a Amount) IsValid() bool {
+	return a >= 0 && a <= MaxAmount
+}
+
+// 带范围检查的加法
+func (a Amount) Add(b Amount) (Amount, error) {
+	if !a.IsValid() || !b.IsValid() || a > MaxAmount-b {
+		return 0, ErrAmountOverflow
+	}
+	return a + b, nil
+}
+
+// 带范围检查的减法
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if !a.IsValid() || !b.IsValid() || a < b {
+		return 0, ErrAmountOverflow
+	}
+	return a - b, nil
+}
+
+// 格式化为十进制字符串，去掉末尾多余的0，例如"1.25"
+func (a Amount) String() string {
+	sign := ""
+	value := int64(a)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	coins := value / int64(CoinUnit)
+	units := value % int64(CoinUnit)
+	if units == 0 {
+		return fmt.Sprintf("%s%d", sign, coins)
+	}
+	frac := strings.TrimRight(fmt.Sprintf("%08d", units), "0")
+	return fmt.Sprintf("%s%d.%s", sign, coins, frac)
+}
+
+// JSON编码为数字，例如1.25
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// JSON解码，支持数字(1.25)和字符串("1.25")两种形式
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	str := strings.Trim(string(data), "\"")
+	amount, err := ParseAmount(str)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
@@ -0,0 +1,87 @@
+package BLC
+
+import "testing"
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		str  string
+		want Amount
+		ok   bool
+	}{
+		{"1", CoinUnit, true},
+		{"1.", CoinUnit, true},
+		{".5", CoinUnit / 2, true},
+		{"0.00000001", 1, true},
+		{" 1.25 ", 125000000, true},
+		{"21000000", MaxAmount, true},
+		{"0", 0, true},
+		{"-0", 0, false},
+		{"-1", 0, false},
+		{"", 0, false},
+		{".", 0, false},
+		{"1.000000001", 0, false},
+		{"0.123456789", 0, false},
+		{"1e8", 0, false},
+		{"1.2.3", 0, false},
+		{"+1", 0, false},
+		{"21000000.00000001", 0, false},
+		{"92233720368", 0, false},
+		{"9223372036854775807", 0, false},
+		{"99999999999999999999", 0, false},
+	}
+	for _, test := range tests {
+		got, err := ParseAmount(test.str)
+		if (err == nil) != test.ok {
+			t.Errorf("ParseAmount(%q) error = %v, want ok %v", test.str, err, test.ok)
+			continue
+		}
+		if test.ok && got != test.want {
+			t.Errorf("ParseAmount(%q) = %d, want %d", test.str, got, test.want)
+		}
+	}
+}
+
+func TestAmountAddSub(t *testing.T) {
+	tests := []struct {
+		a, b   Amount
+		sum    Amount
+		sumOK  bool
+		diff   Amount
+		diffOK bool
+	}{
+		{CoinUnit, CoinUnit, 2 * CoinUnit, true, 0, true},
+		{MaxAmount, 0, MaxAmount, true, MaxAmount, true},
+		{MaxAmount, 1, 0, false, MaxAmount - 1, true},
+		{MaxAmount - 1, 1, MaxAmount, true, MaxAmount - 2, true},
+		{1, 2, 3, true, 0, false},
+		{-1, 1, 0, false, 0, false},
+		{1, -1, 0, false, 0, false},
+		{MaxAmount + 1, 0, 0, false, 0, false},
+	}
+	for _, test := range tests {
+		sum, err := test.a.Add(test.b)
+		if (err == nil) != test.sumOK || (test.sumOK && sum != test.sum) {
+			t.Errorf("%d.Add(%d) = %d, %v", test.a, test.b, sum, err)
+		}
+		diff, err := test.a.Sub(test.b)
+		if (err == nil) != test.diffOK || (test.diffOK && diff != test.diff) {
+			t.Errorf("%d.Sub(%d) = %d, %v", test.a, test.b, diff, err)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	tests := map[Amount]string{
+		0:         "0",
+		1:         "0.00000001",
+		CoinUnit:  "1",
+		125000000: "1.25",
+		-30000000: "-0.3",
+		MaxAmount: "21000000",
+	}
+	for amount, want := range tests {
+		if got := amount.String(); got != want {
+			t.Errorf("Amount(%d).String() = %q, want %q", int64(amount), got, want)
+		}
+	}
+}
@@ -1,39 +1,222 @@
 package BLC
 
 import (
-	"log"
+	"bytes"
+	"errors"
+	"fmt"
 
 	"github.com/boltdb/bolt"
 )
 
 // 区块链迭代器管理文件
+// 向后迭代：从指定区块(默认最新区块)沿PrevBlockHash遍历到创世区块
+// 向前迭代：沿主链从指定高度或区块遍历到迭代器创建时的最新区块
+// 用法：
+//   for it := blockchain.Iterator(); it.HasNext(); {
+//       block := it.Next()
+//   }
+//   if err := it.Err(); err != nil {...}
+
+// ForEachBlock的访问函数返回该错误时停止遍历，ForEachBlock返回nil
+var ErrStopIteration = errors.New("stop iteration")
 
 // 迭代器基本结构
 type BlockChainIterator struct {
 	DB          *bolt.DB //迭代目标
 	CurrentHash []byte   //单次迭代目标的哈希
+
+	forward bool     // 是否向前迭代
+	hashes  [][]byte // 向前迭代的主链区块哈希，按高度从低到高排列
+	index   int      // 向前迭代的下一个区块在hashes中的位置
+	err     error
 }
 
-// 创建迭代器对象
+// 创建迭代器对象，从最新区块向后迭代
 func (bc *BlockChain) Iterator() *BlockChainIterator {
-	return &BlockChainIterator{DB: bc.DB, CurrentHash: bc.Tip}
+	return bc.IteratorFrom(bc.Tip)
+}
+
+// 从指定区块向后迭代到创世区块
+func (bc *BlockChain) IteratorFrom(hash []byte) *BlockChainIterator {
+	return &BlockChainIterator{DB: bc.DB, CurrentHash: hash}
+}
+
+// 沿主链从指定高度向前迭代，height小于1时从创世区块开始
+func (bc *BlockChain) ForwardIterator(height int64) *BlockChainIterator {
+	if height < 1 {
+		height = 1
+	}
+	bcit := &BlockChainIterator{DB: bc.DB, forward: true}
+	bcit.hashes, bcit.err = bc.mainChainHashesFrom(height, 0)
+	if len(bcit.hashes) > 0 {
+		bcit.CurrentHash = bcit.hashes[0]
+	}
+	return bcit
+}
+
+// 沿主链从指定区块向前迭代，区块不在主链上时Err返回错误
+func (bc *BlockChain) ForwardIteratorFrom(hash []byte) *BlockChainIterator {
+	block := bc.GetBlock(hash)
+	if block == nil {
+		return &BlockChainIterator{DB: bc.DB, forward: true, err: fmt.Errorf("block [%x] not found", hash)}
+	}
+	bcit := bc.ForwardIterator(block.Height)
+	if bcit.err == nil && (len(bcit.hashes) == 0 || !bytes.Equal(bcit.hashes[0], hash)) {
+		bcit.hashes, bcit.CurrentHash = nil, nil
+		bcit.err = fmt.Errorf("block [%x] is not on the main chain", hash)
+	}
+	return bcit
+}
+
+// 是否还有区块，出错后返回false
+func (bcit *BlockChainIterator) HasNext() bool {
+	if bcit.err != nil {
+		return false
+	}
+	if bcit.forward {
+		return bcit.index < len(bcit.hashes)
+	}
+	return !isNullHash(bcit.CurrentHash)
+}
+
+// 迭代过程中的错误，正常结束时为nil
+func (bcit *BlockChainIterator) Err() error {
+	return bcit.err
 }
 
 // 实现迭代器函数next，获取到每一个区块
+// 没有更多区块或出错时返回nil，错误通过Err获取
 func (bcit *BlockChainIterator) Next() *Block {
+	if !bcit.HasNext() {
+		return nil
+	}
+	block, err := bcit.readBlock(bcit.CurrentHash)
+	if err != nil {
+		bcit.err = err
+		return nil
+	}
+	// 更新迭代器中区块的哈希值
+	if bcit.forward {
+		bcit.index++
+		bcit.CurrentHash = nil
+		if bcit.index < len(bcit.hashes) {
+			bcit.CurrentHash = bcit.hashes[bcit.index]
+		}
+	} else {
+		bcit.CurrentHash = block.PrevBlockHash
+	}
+	return block
+}
+
+func (bcit *BlockChainIterator) readBlock(hash []byte) (*Block, error) {
 	var block *Block
 	err := bcit.DB.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(blockTableName))
-		if b != nil {
-			currentBlockBytes := b.Get(bcit.CurrentHash)
-			block = DeserializeBlock(currentBlockBytes)
-			// 更新迭代器中区块的哈希值
-			bcit.CurrentHash = block.PrevBlockHash
+		if b == nil {
+			return errors.New("block bucket not found")
+		}
+		blockBytes := b.Get(hash)
+		if blockBytes == nil {
+			return fmt.Errorf("block [%x] not found", hash)
+		}
+		var err error
+		block, err = DecodeBlock(blockBytes)
+		return err
+	})
+	return block, err
+}
+
+// 创世区块的PrevBlockHash为空(早期版本的数据库中为全0)
+func isNullHash(hash []byte) bool {
+	for _, b := range hash {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// 主链上从指定高度开始的区块哈希，按高度从低到高排列；limit大于0时最多返回limit个
+// 由高度索引读取，不能使用高度索引时从最新区块向后遍历到该高度
+func (bc *BlockChain) mainChainHashesFrom(height int64, limit int) ([][]byte, error) {
+	var hashes [][]byte
+	indexed, err := bc.viewHeightIndex(func(index *bolt.Bucket, tipHeight int64) error {
+		c := index.Cursor()
+		// 高度键为8字节，tip键排在所有高度键之后
+		for k, v := c.Seek(heightIndexKey(height)); k != nil && len(k) == 8; k, v = c.Next() {
+			if limit > 0 && len(hashes) == limit {
+				break
+			}
+			hashes = append(hashes, append([]byte{}, v...))
 		}
 		return nil
 	})
-	if err != nil {
-		log.Panicf("iterator the db failed! %v\n", err)
+	if err != nil || indexed {
+		return hashes, err
 	}
-	return block
+
+	bcit := bc.Iterator()
+	for bcit.HasNext() {
+		block := bcit.Next()
+		if block == nil || block.Height < height {
+			break
+		}
+		hashes = append(hashes, block.Hash)
+	}
+	if err := bcit.Err(); err != nil {
+		return nil, err
+	}
+	// 倒序
+	for i, j := 0, len(hashes)-1; i < j; i, j = i+1, j-1 {
+		hashes[i], hashes[j] = hashes[j], hashes[i]
+	}
+	if limit > 0 && len(hashes) > limit {
+		hashes = hashes[:limit]
+	}
+	return hashes, nil
+}
+
+// 按高度访问主链上from到to之间(包括两端)的区块
+// from小于1时从创世区块开始，to小于1时到最新区块为止；from大于to时从高到低访问
+// fn返回ErrStopIteration时停止遍历并返回nil，返回其他错误时停止遍历并返回该错误
+func (bc *BlockChain) ForEachBlock(from, to int64, fn func(block *Block) error) error {
+	if from < 1 {
+		from = 1
+	}
+	if to < 1 {
+		to = bc.Height()
+	}
+	var bcit *BlockChainIterator
+	if from <= to {
+		bcit = bc.ForwardIterator(from)
+	} else if hash, indexed := bc.mainChainHash(from); indexed && hash != nil {
+		bcit = bc.IteratorFrom(hash)
+	} else {
+		bcit = bc.Iterator()
+	}
+	for bcit.HasNext() {
+		block := bcit.Next()
+		if block == nil {
+			break
+		}
+		if from <= to {
+			if block.Height > to {
+				break
+			}
+		} else {
+			if block.Height > from {
+				continue
+			}
+			if block.Height < to {
+				break
+			}
+		}
+		if err := fn(block); err != nil {
+			if err == ErrStopIteration {
+				return nil
+			}
+			return err
+		}
+	}
+	return bcit.Err()
 }
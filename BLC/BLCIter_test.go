@@ -0,0 +1,215 @@
+package BLC
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// 创建高度为height的测试链，除创世区块外均为空区块
+func newTestChainWithHeight(t *testing.T, height int64) *BlockChain {
+	t.Helper()
+	blockchain := newTestChain(t, "alice")
+	for blockchain.Height() < height {
+		blockchain.AddBlock(nil)
+	}
+	return blockchain
+}
+
+func blockHeights(t *testing.T, bcit *BlockChainIterator) []int64 {
+	t.Helper()
+	var heights []int64
+	for bcit.HasNext() {
+		heights = append(heights, bcit.Next().Height)
+	}
+	if err := bcit.Err(); err != nil {
+		t.Fatalf("iterate the blockchain failed: %v", err)
+	}
+	return heights
+}
+
+func TestIterator(t *testing.T) {
+	blockchain := newTestChainWithHeight(t, 4)
+
+	if got := blockHeights(t, blockchain.Iterator()); !reflect.DeepEqual(got, []int64{4, 3, 2, 1}) {
+		t.Fatalf("Iterator heights = %v", got)
+	}
+	tests := []struct {
+		from int64
+		want []int64
+	}{
+		{-1, []int64{1, 2, 3, 4}},
+		{0, []int64{1, 2, 3, 4}},
+		{1, []int64{1, 2, 3, 4}},
+		{3, []int64{3, 4}},
+		{4, []int64{4}},
+		{5, nil},
+	}
+	for _, test := range tests {
+		if got := blockHeights(t, blockchain.ForwardIterator(test.from)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ForwardIterator(%d) heights = %v, want %v", test.from, got, test.want)
+		}
+	}
+
+	// 迭代器只遍历到创建时的最新区块
+	bcit := blockchain.ForwardIterator(1)
+	blockchain.AddBlock(nil)
+	if got := blockHeights(t, bcit); !reflect.DeepEqual(got, []int64{1, 2, 3, 4}) {
+		t.Fatalf("ForwardIterator after AddBlock heights = %v", got)
+	}
+	if bcit.Next() != nil {
+		t.Fatal("Next after the last block returned a block")
+	}
+}
+
+func TestForwardIteratorFrom(t *testing.T) {
+	blockchain := newTestChainWithHeight(t, 3)
+	genesis := blockchain.ForwardIterator(1).Next()
+
+	if got := blockHeights(t, blockchain.ForwardIteratorFrom(genesis.Hash)); !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Fatalf("ForwardIteratorFrom(genesis) heights = %v", got)
+	}
+
+	// 分叉区块不在主链上
+	side := newTestBlock(t, blockchain, genesis, "alice", "bob", CoinUnit)
+	if acceptTestBlock(t, blockchain, side) {
+		t.Fatal("side block became the tip")
+	}
+	bcit := blockchain.ForwardIteratorFrom(side.Hash)
+	if bcit.HasNext() || bcit.Err() == nil {
+		t.Fatal("ForwardIteratorFrom(side block) did not fail")
+	}
+
+	bcit = blockchain.ForwardIteratorFrom([]byte("missing"))
+	if bcit.HasNext() || bcit.Err() == nil {
+		t.Fatal("ForwardIteratorFrom(missing block) did not fail")
+	}
+}
+
+func TestForEachBlock(t *testing.T) {
+	blockchain := newTestChainWithHeight(t, 5)
+
+	tests := []struct {
+		from, to int64
+		want     []int64
+	}{
+		{1, 5, []int64{1, 2, 3, 4, 5}},
+		{0, 0, []int64{1, 2, 3, 4, 5}},
+		{-3, 2, []int64{1, 2}},
+		{1, 1, []int64{1}},
+		{2, 4, []int64{2, 3, 4}},
+		{4, -1, []int64{4, 5}},
+		{5, 1, []int64{5, 4, 3, 2, 1}},
+		{4, 2, []int64{4, 3, 2}},
+		{3, 9, []int64{3, 4, 5}},
+		{9, 4, []int64{5, 4}},
+		{7, 6, nil},
+	}
+	for _, test := range tests {
+		var got []int64
+		err := blockchain.ForEachBlock(test.from, test.to, func(block *Block) error {
+			got = append(got, block.Height)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("ForEachBlock(%d, %d) error: %v", test.from, test.to, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ForEachBlock(%d, %d) heights = %v, want %v", test.from, test.to, got, test.want)
+		}
+	}
+
+	var visited int
+	err := blockchain.ForEachBlock(1, 5, func(block *Block) error {
+		visited++
+		if block.Height == 2 {
+			return ErrStopIteration
+		}
+		return nil
+	})
+	if err != nil || visited != 2 {
+		t.Fatalf("ForEachBlock with ErrStopIteration = %v after %d blocks", err, visited)
+	}
+
+	errFn := errors.New("fn failed")
+	if err := blockchain.ForEachBlock(5, 1, func(*Block) error { return errFn }); err != errFn {
+		t.Fatalf("ForEachBlock returned %v, want the error of fn", err)
+	}
+}
+
+// 主链上各高度的区块哈希
+func mainChainHashesAt(t *testing.T, blockchain *BlockChain, heights ...int64) [][]byte {
+	t.Helper()
+	var hashes [][]byte
+	for _, height := range heights {
+		block := blockchain.GetBlockByHeight(height)
+		if block == nil {
+			t.Fatalf("block at height %d not found", height)
+		}
+		hashes = append(hashes, block.Hash)
+	}
+	return hashes
+}
+
+func TestBlockLocator(t *testing.T) {
+	blockchain := newTestChainWithHeight(t, 25)
+
+	want := mainChainHashesAt(t, blockchain, 25, 24, 23, 22, 21, 20, 19, 18, 17, 16, 14, 10, 2, 1)
+	locator, err := blockchain.BlockLocator()
+	if err != nil {
+		t.Fatalf("BlockLocator error: %v", err)
+	}
+	if !reflect.DeepEqual(locator, want) {
+		t.Fatalf("BlockLocator returned %d hashes, want %d", len(locator), len(want))
+	}
+
+	// 不是数据库中最新区块的视图从最新区块向后遍历
+	view := &BlockChain{DB: blockchain.DB, Tip: blockchain.GetBlockByHeight(12).Hash}
+	locator, err = view.BlockLocator()
+	if err != nil {
+		t.Fatalf("BlockLocator of the view error: %v", err)
+	}
+	if want := mainChainHashesAt(t, blockchain, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 1); !reflect.DeepEqual(locator, want) {
+		t.Fatalf("BlockLocator of the view returned %d hashes, want %d", len(locator), len(want))
+	}
+
+	hashes, err := blockchain.MainChainHashes()
+	if err != nil {
+		t.Fatalf("MainChainHashes error: %v", err)
+	}
+	if len(hashes) != 25 || !reflect.DeepEqual(hashes[9:12], mainChainHashesAt(t, blockchain, 10, 11, 12)) {
+		t.Fatalf("MainChainHashes returned %d hashes", len(hashes))
+	}
+}
+
+func TestHashesAfterLocator(t *testing.T) {
+	blockchain := newTestChainWithHeight(t, 8)
+	side := newTestBlock(t, blockchain, blockchain.GetBlockByHeight(5), "alice", "bob", CoinUnit)
+	if acceptTestBlock(t, blockchain, side) {
+		t.Fatal("side chain became the main chain")
+	}
+
+	tests := []struct {
+		locator [][]byte
+		limit   int
+		want    [][]byte
+	}{
+		{mainChainHashesAt(t, blockchain, 4), 3, mainChainHashesAt(t, blockchain, 5, 6, 7)},
+		{mainChainHashesAt(t, blockchain, 6), 10, mainChainHashesAt(t, blockchain, 7, 8)},
+		{mainChainHashesAt(t, blockchain, 8), 10, nil},
+		// 分叉链上的区块不是分叉点
+		{append([][]byte{side.Hash}, mainChainHashesAt(t, blockchain, 5)...), 2, mainChainHashesAt(t, blockchain, 6, 7)},
+		// 没有共同区块时从创世区块开始
+		{[][]byte{[]byte("unknown")}, 2, mainChainHashesAt(t, blockchain, 1, 2)},
+	}
+	for index, test := range tests {
+		hashes, err := blockchain.HashesAfterLocator(test.locator, test.limit)
+		if err != nil {
+			t.Fatalf("test %d: HashesAfterLocator error: %v", index, err)
+		}
+		if !reflect.DeepEqual(hashes, test.want) {
+			t.Errorf("test %d: HashesAfterLocator returned %d hashes, want %d", index, len(hashes), len(test.want))
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package BLC
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// 节点封禁管理文件
+// 节点违反协议时累加违规分数，达到阈值后断开连接并在一段时间内拒绝其连接
+// 封禁列表保存在数据库的banned表中，节点重启后仍然有效
+
+// 封禁表名称
+const banTableName = "banned"
+
+const (
+	// 违规分数达到该值时封禁
+	banThreshold = 100
+	// 默认封禁时间
+	defaultBanDuration = 24 * time.Hour
+)
+
+// 各类违规行为的分数
+const (
+	banScoreInvalidBlock     = 100 // 区块验证失败
+	banScoreInvalidHeaders   = 100 // 区块头链无效
+	banScoreOversizedMessage = 100 // 消息超过长度上限
+	banScoreMalformedMessage = 20  // 消息无法解析
+	banScoreMessageFlood     = 20  // 短时间内发送过多消息
+	banScoreProtocol         = 10  // 握手前发送消息、重复发送version等
+)
+
+// 封禁条目
+type BanEntry struct {
+	Addr    string // 封禁的地址：主机(IP)或主机:端口
+	Created int64  // 封禁时间
+	Until   int64  // 解除封禁的时间
+	Reason  string // 封禁原因
+}
+
+// 封禁管理器
+type BanManager struct {
+	db      *bolt.DB
+	mutex   sync.Mutex
+	entries map[string]*BanEntry
+}
+
+// 创建封禁管理器，从数据库中加载未过期的封禁条目
+func NewBanManager(db *bolt.DB) (*BanManager, error) {
+	manager := &BanManager{db: db, entries: make(map[string]*BanEntry)}
+	now := time.Now().Unix()
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(banTableName))
+		if err != nil {
+			return err
+		}
+		var expired [][]byte
+		err = b.ForEach(func(k, v []byte) error {
+			var entry BanEntry
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&entry); err != nil {
+				return fmt.Errorf("decode ban entry [%s] failed: %v", k, err)
+			}
+			if entry.Until <= now {
+				expired = append(expired, append([]byte{}, k...))
+				return nil
+			}
+			manager.entries[entry.Addr] = &entry
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+// 封禁地址，已封禁时更新解除时间与原因
+func (manager *BanManager) Ban(addr string, duration time.Duration, reason string) error {
+	if addr == "" {
+		return fmt.Errorf("ban address is empty")
+	}
+	if duration <= 0 {
+		return fmt.Errorf("invalid ban duration [%v]", duration)
+	}
+	now := time.Now()
+	entry := &BanEntry{addr, now.Unix(), now.Add(duration).Unix(), reason}
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(entry); err != nil {
+		return err
+	}
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	err := manager.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(banTableName)).Put([]byte(addr), buffer.Bytes())
+	})
+	if err != nil {
+		return fmt.Errorf("save ban entry [%s] failed: %v", addr, err)
+	}
+	manager.entries[addr] = entry
+	return nil
+}
+
+// 解除封禁，地址未被封禁时返回false
+func (manager *BanManager) Unban(addr string) (bool, error) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	if _, ok := manager.entries[addr]; !ok {
+		return false, nil
+	}
+	err := manager.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(banTableName)).Delete([]byte(addr))
+	})
+	if err != nil {
+		return false, fmt.Errorf("delete ban entry [%s] failed: %v", addr, err)
+	}
+	delete(manager.entries, addr)
+	return true, nil
+}
+
+// 清空封禁列表
+func (manager *BanManager) Clear() error {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	err := manager.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(banTableName)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		_, err := tx.CreateBucket([]byte(banTableName))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("clear ban list failed: %v", err)
+	}
+	manager.entries = make(map[string]*BanEntry)
+	return nil
+}
+
+// 未过期的封禁条目，按解除时间排序
+func (manager *BanManager) List() []*BanEntry {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	now := time.Now().Unix()
+	var list []*BanEntry
+	for _, entry := range manager.entries {
+		if entry.Until > now {
+			copied := *entry
+			list = append(list, &copied)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Until != list[j].Until {
+			return list[i].Until < list[j].Until
+		}
+		return list[i].Addr < list[j].Addr
+	})
+	return list
+}
+
+// 判断地址是否被封禁：地址本身或其主机被封禁均视为封禁
+func (manager *BanManager) IsBanned(addrs ...string) bool {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	now := time.Now().Unix()
+	for _, addr := range addrs {
+		if addr == "" {
+			continue
+		}
+		candidates := []string{addr}
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			candidates = append(candidates, host)
+		}
+		for _, candidate := range candidates {
+			if entry, ok := manager.entries[candidate]; ok && entry.Until > now {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 违规封禁的键：回环地址上的节点共用同一主机，按主机:端口封禁，避免一次封禁拒绝所有本机节点；
+// 其他地址按主机封禁
+func banKey(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if isLoopbackHost(host) {
+		return addr
+	}
+	return host
+}
+
+// 对方的封禁键：对方发起的回环连接使用临时端口，换个端口重新连接即可逃避按主机:端口的封禁，
+// 因此改用其在version消息中声明的监听地址；声明的地址不是回环地址时仍按连接地址，
+// 避免本机节点冒用其他主机的地址使其被封禁
+func peerBanKey(addr string, inbound bool, listenAddr string) string {
+	if inbound && isLoopbackAddr(addr) && isLoopbackAddr(listenAddr) {
+		return listenAddr
+	}
+	return banKey(addr)
+}
+
+// 是否为回环地址(主机:端口)
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	return err == nil && isLoopbackHost(host)
+}
+
+// 是否为回环主机
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// 违规错误：处理消息时发现对方违反协议
+type misbehaviorError struct {
+	score int
+	err   error
+}
+
+func (e *misbehaviorError) Error() string {
+	return e.err.Error()
+}
+
+// 生成违规错误
+func misbehavior(score int, err error) error {
+	return &misbehaviorError{score, err}
+}
+
+// 对方违规：累加违规分数，达到阈值时封禁并断开连接
+// 返回是否已封禁
+func (node *Node) misbehaving(peer *Peer, score int, reason string) bool {
+	total := peer.addBanScore(score)
+	netLog.Warnf("节点 [%s] 违规：%s，违规分数 [%d]\n", peer, reason, total)
+	if total < banThreshold {
+		return false
+	}
+	addr := peer.banAddr()
+	if err := node.banManager.Ban(addr, defaultBanDuration, reason); err != nil {
+		netLog.Warnf("封禁节点 [%s] 失败：%v\n", addr, err)
+	} else {
+		netLog.Infof("封禁节点 [%s]，直到 [%s]\n", addr, time.Now().Add(defaultBanDuration).Format("2006-01-02 15:04:05"))
+	}
+	peer.Close()
+	return true
+}
+
+// 手动封禁地址，并断开与被封禁地址的连接
+func (node *Node) Ban(addr string, duration time.Duration, reason string) error {
+	if err := node.banManager.Ban(addr, duration, reason); err != nil {
+		return err
+	}
+	for _, peer := range node.Peers() {
+		if node.banManager.IsBanned(peer.Addr, peer.banAddr()) {
+			netLog.Infof("断开被封禁的节点 [%s]\n", peer)
+			peer.Close()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package BLC
+
+import "testing"
+
+func TestBanKey(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"localhost:3001", "localhost:3001"},
+		{"127.0.0.1:3001", "127.0.0.1:3001"},
+		{"[::1]:3001", "[::1]:3001"},
+		{"10.0.0.5:51234", "10.0.0.5"},
+		{"node0:8333", "node0"},
+		{"10.0.0.5", "10.0.0.5"},
+	}
+	for _, test := range tests {
+		if got := banKey(test.addr); got != test.want {
+			t.Errorf("banKey(%q) = %q, want %q", test.addr, got, test.want)
+		}
+	}
+}
+
+func TestPeerBanKey(t *testing.T) {
+	tests := []struct {
+		addr       string
+		inbound    bool
+		listenAddr string
+		want       string
+	}{
+		// 对方发起的回环连接按声明的回环监听地址封禁
+		{"127.0.0.1:51234", true, "127.0.0.1:3001", "127.0.0.1:3001"},
+		{"[::1]:51234", true, "localhost:3001", "localhost:3001"},
+		// 握手前或声明的不是回环地址时按连接地址
+		{"127.0.0.1:51234", true, "", "127.0.0.1:51234"},
+		{"127.0.0.1:51234", true, "10.0.0.5:3001", "127.0.0.1:51234"},
+		// 非回环连接不使用声明的地址
+		{"10.0.0.5:51234", true, "127.0.0.1:3001", "10.0.0.5"},
+		// 本节点发起的连接按拨号地址
+		{"127.0.0.1:3002", false, "127.0.0.1:3001", "127.0.0.1:3002"},
+	}
+	for _, test := range tests {
+		if got := peerBanKey(test.addr, test.inbound, test.listenAddr); got != test.want {
+			t.Errorf("peerBanKey(%q, %v, %q) = %q, want %q", test.addr, test.inbound, test.listenAddr, got, test.want)
+		}
+	}
+}
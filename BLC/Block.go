@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/gob"
-	"log"
 	"time"
 )
 
@@ -68,20 +67,29 @@ func (block *Block) Serialize() []byte {
 	encoder := gob.NewEncoder(&buffer)
 	// 编码序列化
 	if err := encoder.Encode(block); nil != err {
-		log.Panicf("serialize the block to []byte failed %v\n", err)
+		chainLog.Panicf("serialize the block to []byte failed %v\n", err)
 	}
 	return buffer.Bytes()
 }
 
 // 区块数据反序列化
 func DeserializeBlock(blockBytes []byte) *Block {
+	block, err := DecodeBlock(blockBytes)
+	if err != nil {
+		chainLog.Panicf("deserialize the block to []byte failed %v\n", err)
+	}
+	return block
+}
+
+// 区块数据反序列化，用于解析来自网络等不可信来源的数据
+func DecodeBlock(blockBytes []byte) (*Block, error) {
 	var block Block
 	// 新建decoder对象
 	decoder := gob.NewDecoder(bytes.NewBuffer(blockBytes))
 	if err := decoder.Decode(&block); err != nil {
-		log.Panicf("deserialize the block to []byte failed %v\n", err)
+		return nil, err
 	}
-	return &block
+	return &block, nil
 }
 
 // 把指定区块的所有交易结构序列化(类似Merkle的哈希计算方法)
@@ -94,3 +102,22 @@ func (block *Block) HashTransaction() []byte {
 	txHash := sha256.Sum256(bytes.Join(txHashes, []byte{}))
 	return txHash[:]
 }
+
+// 区块中是否有交易的输入或输出涉及指定地址
+func (block *Block) HasAddress(address string) bool {
+	for _, tx := range block.Txs {
+		if !tx.IsCoinbaseTransaction() {
+			for _, in := range tx.Vins {
+				if in.CheckPubkeyWithAddress(address) {
+					return true
+				}
+			}
+		}
+		for _, out := range tx.Vouts {
+			if out.CheckPubkeyWithAddress(address) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,328 @@
+package BLC
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// 区块验证与接收管理文件
+// 用于接收来自其他节点的区块：验证、存储，并在出现更长的链时切换最新区块
+
+// 区块时间戳允许超前本地时间的最大值
+const maxBlockTimeOffset = 2 * time.Hour
+
+var (
+	// 区块已存在
+	ErrBlockExists = errors.New("block already exists")
+	// 父区块不存在(孤块)
+	ErrOrphanBlock = errors.New("orphan block")
+)
+
+// 打开指定的区块链数据库，数据库或区块表不存在时自动创建(此时为空链，Tip为nil)
+func OpenBlockChain(dbFile string) (*BlockChain, error) {
+	db, err := bolt.Open(dbFile, 0600, &bolt.Options{Timeout: dbOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("open the db [%s] failed: %v", dbFile, err)
+	}
+	var tip []byte
+	err = db.Update(func(tx *bolt.Tx) error {
+		if err := checkDBVersion(tx); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucketIfNotExists([]byte(blockTableName))
+		if err != nil {
+			return err
+		}
+		if latest := b.Get([]byte("l")); latest != nil {
+			tip = append([]byte{}, latest...)
+			return nil
+		}
+		// 空链从创世区块开始维护地址索引与高度索引
+		if _, err := tx.CreateBucketIfNotExists([]byte(addrIndexTableName)); err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte(heightIndexTableName))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init the db [%s] failed: %v", dbFile, err)
+	}
+	return &BlockChain{DB: db, Tip: tip}, nil
+}
+
+// 判断是否为空链(尚未有创世区块)
+func (blockchain *BlockChain) IsEmpty() bool {
+	return len(blockchain.Tip) == 0
+}
+
+// 根据哈希获取区块，不存在时返回nil
+func (blockchain *BlockChain) GetBlock(hash []byte) *Block {
+	var block *Block
+	blockchain.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blockTableName))
+		if b != nil && len(hash) > 0 {
+			if blockBytes := b.Get(hash); blockBytes != nil {
+				block = DeserializeBlock(blockBytes)
+			}
+		}
+		return nil
+	})
+	return block
+}
+
+// 判断区块是否存在
+func (blockchain *BlockChain) HasBlock(hash []byte) bool {
+	var exist bool
+	blockchain.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blockTableName))
+		exist = b != nil && len(hash) > 0 && b.Get(hash) != nil
+		return nil
+	})
+	return exist
+}
+
+// 获取最新区块，空链返回nil
+func (blockchain *BlockChain) TipBlock() *Block {
+	return blockchain.GetBlock(blockchain.Tip)
+}
+
+// 获取最新区块高度，空链返回0
+func (blockchain *BlockChain) Height() int64 {
+	var height int64
+	indexed, _ := blockchain.viewHeightIndex(func(index *bolt.Bucket, tipHeight int64) error {
+		height = tipHeight
+		return nil
+	})
+	if indexed {
+		return height
+	}
+	if block := blockchain.TipBlock(); block != nil {
+		return block.Height
+	}
+	return 0
+}
+
+// 根据高度获取主链上的区块，不存在时返回nil
+func (blockchain *BlockChain) GetBlockByHeight(height int64) *Block {
+	if hash, indexed := blockchain.mainChainHash(height); indexed {
+		if hash == nil {
+			return nil
+		}
+		return blockchain.GetBlock(hash)
+	}
+	// 不能使用高度索引时从最新区块向前查找
+	block := blockchain.TipBlock()
+	for block != nil && block.Height > height {
+		block = blockchain.GetBlock(block.PrevBlockHash)
+	}
+	if block == nil || block.Height != height {
+		return nil
+	}
+	return block
+}
+
+// 判断区块是否位于主链上
+func (blockchain *BlockChain) IsMainChain(block *Block) bool {
+	if hash, indexed := blockchain.mainChainHash(block.Height); indexed {
+		return bytes.Equal(hash, block.Hash)
+	}
+	main := blockchain.GetBlockByHeight(block.Height)
+	return main != nil && bytes.Equal(main.Hash, block.Hash)
+}
+
+// 主链区块哈希列表，按高度从创世区块到最新区块排列
+func (blockchain *BlockChain) MainChainHashes() ([][]byte, error) {
+	hashes, err := blockchain.mainChainHashesFrom(1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("iterate the blockchain failed: %v", err)
+	}
+	return hashes, nil
+}
+
+// 区块定位器：从最新区块开始，前10个逐个取，之后步长加倍，最后包含创世区块
+// 对方据此找到双方主链的分叉点
+func (blockchain *BlockChain) BlockLocator() ([][]byte, error) {
+	var locator [][]byte
+	indexed, err := blockchain.viewHeightIndex(func(index *bolt.Bucket, tipHeight int64) error {
+		for _, height := range locatorHeights(tipHeight) {
+			hash := index.Get(heightIndexKey(height))
+			if hash == nil {
+				return fmt.Errorf("height [%d] not found in the height index", height)
+			}
+			locator = append(locator, append([]byte{}, hash...))
+		}
+		return nil
+	})
+	if err != nil || indexed {
+		return locator, err
+	}
+	hashes, err := blockchain.MainChainHashes()
+	if err != nil {
+		return nil, err
+	}
+	for _, height := range locatorHeights(int64(len(hashes))) {
+		locator = append(locator, hashes[height-1])
+	}
+	return locator, nil
+}
+
+// 区块定位器中区块的高度，从tipHeight开始由高到低排列
+func locatorHeights(tipHeight int64) []int64 {
+	var heights []int64
+	step := int64(1)
+	for height := tipHeight; height >= 1; height -= step {
+		heights = append(heights, height)
+		if len(heights) >= 10 {
+			step *= 2
+		}
+	}
+	if len(heights) > 0 && heights[len(heights)-1] != 1 {
+		heights = append(heights, 1)
+	}
+	return heights
+}
+
+// 根据对方的区块定位器，返回对方缺少的主链区块哈希(最多limit个)
+func (blockchain *BlockChain) HashesAfterLocator(locator [][]byte, limit int) ([][]byte, error) {
+	// 定位器中第一个位于本地主链上的区块即为分叉点，没有时从创世区块开始
+	start := int64(1)
+	for _, hash := range locator {
+		if block := blockchain.GetBlock(hash); block != nil && blockchain.IsMainChain(block) {
+			start = block.Height + 1
+			break
+		}
+	}
+	return blockchain.mainChainHashesFrom(start, limit)
+}
+
+// 验证区块
+//  1. 工作量证明有效，时间戳合理
+//  2. 父区块存在且高度连续(创世区块只能被空链接收)
+//  3. 交易有效：创世区块只包含一笔coinbase交易，其他区块不允许包含coinbase交易，
+//     普通交易基于父区块所在的链验证
+func (blockchain *BlockChain) ValidateBlock(block *Block) error {
+	if len(block.Txs) == 0 {
+		return errors.New("block has no transactions")
+	}
+	if !NewProofOfWork(block).Validate() {
+		return fmt.Errorf("block [%x] has invalid proof of work", block.Hash)
+	}
+	if time.Unix(block.TimeStamp, 0).After(time.Now().Add(maxBlockTimeOffset)) {
+		return fmt.Errorf("block [%x] timestamp is too far in the future", block.Hash)
+	}
+
+	// 创世区块
+	if len(block.PrevBlockHash) == 0 {
+		if !blockchain.IsEmpty() {
+			return fmt.Errorf("genesis block [%x] does not match the local chain", block.Hash)
+		}
+		if block.Height != 1 || len(block.Txs) != 1 || !block.Txs[0].IsCoinbaseTransaction() {
+			return errors.New("invalid genesis block")
+		}
+		coinbase := block.Txs[0]
+		check := &Transaction{nil, coinbase.Vins, coinbase.Vouts}
+		check.HashTransaction()
+		if !bytes.Equal(check.TxHash, coinbase.TxHash) {
+			return fmt.Errorf("coinbase hash mismatch [%x]", coinbase.TxHash)
+		}
+		if len(coinbase.Vouts) != 1 || coinbase.Vouts[0].Value != coinbaseReward {
+			return errors.New("invalid coinbase reward")
+		}
+		return nil
+	}
+
+	parent := blockchain.GetBlock(block.PrevBlockHash)
+	if parent == nil {
+		return ErrOrphanBlock
+	}
+	if block.Height != parent.Height+1 {
+		return fmt.Errorf("block height [%d] does not follow parent height [%d]", block.Height, parent.Height)
+	}
+
+	// 基于父区块所在的链验证交易，区块中前面的交易作为缓存
+	view := &BlockChain{DB: blockchain.DB, Tip: block.PrevBlockHash}
+	var verified []*Transaction
+	for _, tx := range block.Txs {
+		if len(tx.Vins) > 0 && tx.IsCoinbaseTransaction() {
+			return errors.New("coinbase transaction is only allowed in the genesis block")
+		}
+		if err := view.VerifyTransaction(tx, verified); err != nil {
+			return fmt.Errorf("transaction [%x] is invalid: %v", tx.TxHash, err)
+		}
+		verified = append(verified, tx)
+	}
+	return nil
+}
+
+// 接收区块：验证通过后存入数据库，如果区块所在的链比当前主链更长则切换最新区块
+// 以高度比较链的长短：难度固定(targetBit)，每个区块的工作量相同，高度更高即累计工作量更大；
+// 高度相同时保留先收到的链。引入难度调整后需要改为比较累计工作量
+// 返回最新区块是否发生变化
+func (blockchain *BlockChain) AcceptBlock(block *Block) (bool, error) {
+	if blockchain.HasBlock(block.Hash) {
+		return false, ErrBlockExists
+	}
+	if err := blockchain.ValidateBlock(block); err != nil {
+		return false, err
+	}
+
+	tipChanged := block.Height > blockchain.Height()
+	oldTip := blockchain.Tip
+	err := blockchain.DB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blockTableName))
+		if b == nil {
+			return errors.New("block bucket not found")
+		}
+		if err := b.Put(block.Hash, block.Serialize()); err != nil {
+			return err
+		}
+		if tipChanged {
+			if err := b.Put([]byte("l"), block.Hash); err != nil {
+				return err
+			}
+			if err := updateAddressIndex(tx, block.Hash); err != nil {
+				return err
+			}
+			return updateHeightIndex(tx, block.Hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("save block [%x] failed: %v", block.Hash, err)
+	}
+	if tipChanged {
+		blockchain.Tip = block.Hash
+		blockchain.publishTipChanged(oldTip)
+	}
+	return tipChanged, nil
+}
+
+// 最新区块从oldTip切换到newTip时断开与接入的区块
+// disconnected按高度从高到低排列，connected按高度从低到高排列
+func (blockchain *BlockChain) ChainChanges(oldTip, newTip []byte) (disconnected, connected []*Block) {
+	oldBlock := blockchain.GetBlock(oldTip)
+	newBlock := blockchain.GetBlock(newTip)
+	for oldBlock != nil && newBlock != nil && !bytes.Equal(oldBlock.Hash, newBlock.Hash) {
+		if oldBlock.Height >= newBlock.Height {
+			disconnected = append(disconnected, oldBlock)
+			oldBlock = blockchain.GetBlock(oldBlock.PrevBlockHash)
+		} else {
+			connected = append(connected, newBlock)
+			newBlock = blockchain.GetBlock(newBlock.PrevBlockHash)
+		}
+	}
+	// 空链接入创世区块时oldBlock为nil
+	for oldBlock == nil && newBlock != nil {
+		connected = append(connected, newBlock)
+		newBlock = blockchain.GetBlock(newBlock.PrevBlockHash)
+	}
+	for i, j := 0, len(connected)-1; i < j; i, j = i+1, j-1 {
+		connected[i], connected[j] = connected[j], connected[i]
+	}
+	return disconnected, connected
+}
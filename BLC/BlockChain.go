@@ -1,18 +1,26 @@
 package BLC
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
-	"math/big"
 	"os"
-	"strconv"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/boltdb/bolt"
 )
 
-// 数据库名称
-const dbName = "block.db"
+// 数据库文件名
+const dbFileName = "block.db"
+
+// 数据库路径，可通过SetDataDir修改所在目录
+var dbName = dbFileName
+
+// 打开数据库的超时时间，避免数据库被其他进程(例如运行中的节点)占用时无限等待
+const dbOpenTimeout = time.Second
 
 // 表名称
 const blockTableName = "blocks"
@@ -21,6 +29,14 @@ type BlockChain struct {
 	// Block []*Block //区块的切片
 	DB  *bolt.DB // 数据库对象
 	Tip []byte   // 保存最新区块的哈希值
+
+	eventMutex sync.Mutex
+	events     *EventBus // 事件总线，见EventBus.go
+}
+
+// 设置数据目录
+func SetDataDir(dir string) {
+	dbName = filepath.Join(dir, dbFileName)
 }
 
 // 判断数据库文件是否存在
@@ -34,15 +50,14 @@ func dbExist() bool {
 // 初始化区块链
 func CreateBlockChainWithGenesisBlock(address string) *BlockChain {
 	if dbExist() {
-		fmt.Printf("创世区块已存在...")
-		os.Exit(1)
+		chainLog.Fatalf("创世区块已存在...")
 	}
 
 	var latesetBlockHash []byte
 	// 1. 创建或者打开一个数据库
 	db, err := bolt.Open(dbName, 0600, nil)
 	if err != nil {
-		log.Panicf("create db [%s] failed %v\n", dbName, err)
+		chainLog.Panicf("create db [%s] failed %v\n", dbName, err)
 	}
 	// 2. 创建桶
 	db.Update(func(tx *bolt.Tx) error {
@@ -52,7 +67,7 @@ func CreateBlockChainWithGenesisBlock(address string) *BlockChain {
 			// 没找到桶
 			b, err = tx.CreateBucket([]byte(blockTableName))
 			if err != nil {
-				log.Panicf("create db [%s] failed %v\n", blockTableName, err)
+				chainLog.Panicf("create db [%s] failed %v\n", blockTableName, err)
 			}
 		}
 		// 生成一个coinbase交易
@@ -64,14 +79,32 @@ func CreateBlockChainWithGenesisBlock(address string) *BlockChain {
 		// 2. 如何把block结构存入到数据库中--序列化
 		err = b.Put(genesisBlock.Hash, genesisBlock.Serialize())
 		if err != nil {
-			log.Panicf("insert the genesis block failed %v\n", err)
+			chainLog.Panicf("insert the genesis block failed %v\n", err)
 		}
 		latesetBlockHash = genesisBlock.Hash
 		// 存储最新区块的哈希
 		// l：latest
 		err = b.Put([]byte("l"), genesisBlock.Hash)
 		if err != nil {
-			log.Panicf("save the latest hash of genesis block failed %v\n", err)
+			chainLog.Panicf("save the latest hash of genesis block failed %v\n", err)
+		}
+		// 记录数据库版本
+		if err := checkDBVersion(tx); err != nil {
+			chainLog.Panicf("save the db version failed %v\n", err)
+		}
+		// 创建地址索引并索引创世区块
+		if _, err := tx.CreateBucketIfNotExists([]byte(addrIndexTableName)); err != nil {
+			chainLog.Panicf("create db [%s] failed %v\n", addrIndexTableName, err)
+		}
+		if err := updateAddressIndex(tx, genesisBlock.Hash); err != nil {
+			chainLog.Panicf("update the address index failed %v\n", err)
+		}
+		// 创建高度索引并索引创世区块
+		if _, err := tx.CreateBucketIfNotExists([]byte(heightIndexTableName)); err != nil {
+			chainLog.Panicf("create db [%s] failed %v\n", heightIndexTableName, err)
+		}
+		if err := updateHeightIndex(tx, genesisBlock.Hash); err != nil {
+			chainLog.Panicf("update the height index failed %v\n", err)
 		}
 		return nil
 	})
@@ -96,16 +129,23 @@ func (bc *BlockChain) AddBlock(txs []*Transaction) {
 			// 5. 存入数据库
 			err := b.Put(newBlock.Hash, newBlock.Serialize())
 			if err != nil {
-				log.Panicf("insert the new block to db failed %v\n", err)
+				chainLog.Panicf("insert the new block to db failed %v\n", err)
 			}
 			// 更新最新区块的哈希（数据库）
 			err = b.Put([]byte("l"), newBlock.Hash)
 			if err != nil {
-				log.Panicf("update the latest block hash to db failed %v", err)
+				chainLog.Panicf("update the latest block hash to db failed %v", err)
+			}
+			if err := updateAddressIndex(tx, newBlock.Hash); err != nil {
+				chainLog.Panicf("update the address index failed %v\n", err)
+			}
+			if err := updateHeightIndex(tx, newBlock.Hash); err != nil {
+				chainLog.Panicf("update the height index failed %v\n", err)
 			}
 
 			// 更新区块链对象的最新区块哈希
 			bc.Tip = newBlock.Hash
+			bc.publish(&Event{Type: EventBlockConnected, Block: newBlock})
 		}
 		return nil
 	})
@@ -113,57 +153,108 @@ func (bc *BlockChain) AddBlock(txs []*Transaction) {
 
 // 遍历数据库，输出所有区块信息
 func (bc *BlockChain) PrintChain() {
-	var currentBlock *Block
-	bcit := bc.Iterator()
-
 	fmt.Println("打印区块完整信息...")
-
-	for {
+	err := bc.ForEachBlock(bc.Height(), 1, func(block *Block) error {
 		fmt.Println("---------------------------------")
-		currentBlock = bcit.Next()
 		// 输出区块详情
-		fmt.Printf("\tHash：%x\n", currentBlock.Hash)
-		fmt.Printf("\tPrevBlockHash：%x\n", currentBlock.PrevBlockHash)
-		fmt.Printf("\tTimeStamp：%v\n", currentBlock.TimeStamp)
-		fmt.Printf("\tHeight：%d\n", currentBlock.Height)
-		fmt.Printf("\tNonce：%d\n", currentBlock.Nonce)
-		fmt.Printf("\tTransaction：%v\n", currentBlock.Txs)
-		for _, tx := range currentBlock.Txs {
-			fmt.Printf("\t\t----------------------------\n")
-			fmt.Printf("\t\ttx-hash: %x\n", tx.TxHash)
-			fmt.Printf("\t\t输入...\n")
-			for _, vin := range tx.Vins {
-				fmt.Printf("\t\tvin-txHash: %x\n", vin.TxHash)
-				fmt.Printf("\t\tvin-vout: %v\n", vin.Vout)
-				fmt.Printf("\t\tvin-scriptSig: %s\n", vin.ScriptSig)
-			}
-			fmt.Printf("\t\t输出...\n")
-			for _, vout := range tx.Vouts {
-				fmt.Printf("\t\tvout-value: %d\n", vout.Value)
-				fmt.Printf("\t\tvout-scriptPubkey: %s\n", vout.ScriptPubkey)
-			}
-		}
+		printBlock(block)
+		return nil
+	})
+	if err != nil {
+		chainLog.Panicf("iterate the blockchain failed! %v\n", err)
+	}
+}
 
-		// 退出条件
-		var hashInt big.Int
-		hashInt.SetBytes(currentBlock.PrevBlockHash)
-		if big.NewInt(0).Cmp(&hashInt) == 0 {
-			// 遍历到创世区块
-			break
+// 输出区块详情
+func printBlock(block *Block) {
+	printBlockHeader(block)
+	fmt.Printf("\tTransaction：%v\n", block.Txs)
+	for _, tx := range block.Txs {
+		tx.PrintTransaction()
+	}
+}
+
+// 输出区块头
+func printBlockHeader(block *Block) {
+	fmt.Printf("\tHash：%x\n", block.Hash)
+	fmt.Printf("\tPrevBlockHash：%x\n", block.PrevBlockHash)
+	fmt.Printf("\tTimeStamp：%v\n", block.TimeStamp)
+	fmt.Printf("\tHeight：%d\n", block.Height)
+	fmt.Printf("\tNonce：%d\n", block.Nonce)
+}
+
+// 区块筛选条件，零值表示不限制
+type BlockFilter struct {
+	From    int64  // 最低高度
+	To      int64  // 最高高度
+	Since   int64  // 最早的区块时间戳
+	Address string // 只选择输入或输出涉及该地址的区块
+	Limit   int    // 最多选择的区块数量
+	Reverse bool   // 从创世区块开始排列，默认从最新区块开始
+}
+
+// 区块是否满足筛选条件(不包括数量限制)
+func (filter *BlockFilter) Match(block *Block) bool {
+	if filter.From > 0 && block.Height < filter.From {
+		return false
+	}
+	if filter.To > 0 && block.Height > filter.To {
+		return false
+	}
+	if filter.Since > 0 && block.TimeStamp < filter.Since {
+		return false
+	}
+	if filter.Address != "" && !block.HasAddress(filter.Address) {
+		return false
+	}
+	return true
+}
+
+// 选择主链上满足条件的区块，按filter.Reverse排列
+func (blockchain *BlockChain) FilterBlocks(filter *BlockFilter) []*Block {
+	var blocks []*Block
+	low, high := filter.From, filter.To
+	if low < 1 {
+		low = 1
+	}
+	if tip := blockchain.Height(); high < 1 || high > tip {
+		high = tip
+	}
+	if low > high {
+		return nil
+	}
+	from, to := high, low
+	if filter.Reverse {
+		from, to = low, high
+	}
+	err := blockchain.ForEachBlock(from, to, func(block *Block) error {
+		if filter.Match(block) {
+			blocks = append(blocks, block)
+			if filter.Limit > 0 && len(blocks) == filter.Limit {
+				return ErrStopIteration
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		chainLog.Panicf("iterate the blockchain failed! %v\n", err)
 	}
+	return blocks
 }
 
 // 获取blockchain对象
 func BlockchainObject() *BlockChain {
 	// 获取DB
-	db, err := bolt.Open(dbName, 0600, nil)
+	db, err := bolt.Open(dbName, 0600, &bolt.Options{Timeout: dbOpenTimeout})
 	if err != nil {
-		log.Panicf("open the db [%s] failed! %v\n", dbName, err)
+		chainLog.Panicf("open the db [%s] failed! %v\n", dbName, err)
 	}
-	// 获取TIp
+	// 检查数据库版本并获取TIp
 	var tip []byte
-	err = db.View(func(tx *bolt.Tx) error {
+	err = db.Update(func(tx *bolt.Tx) error {
+		if err := checkDBVersion(tx); err != nil {
+			return err
+		}
 		b := tx.Bucket([]byte(blockTableName))
 		if b != nil {
 			tip = b.Get([]byte("l"))
@@ -171,23 +262,70 @@ func BlockchainObject() *BlockChain {
 		return nil
 	})
 	if err != nil {
-		log.Panicf("get the blockchain object failed %v\n", err)
+		db.Close()
+		chainLog.Fatalf("get the blockchain object failed %v\n", err)
 	}
 	return &BlockChain{DB: db, Tip: tip}
 }
 
 // 实现挖矿功能
 // 通过接受交易，生成区块
-func (blockchain *BlockChain) MineNewBlock(from, to, amount []string) {
+// data:附加到第一笔交易的数据输出，为空时不添加
+// 返回打包的新区块，参数不一致、金额无效或余额不足时返回错误
+func (blockchain *BlockChain) MineNewBlock(from, to, amount []string, selector CoinSelector, data []byte) (*Block, error) {
 	var txs []*Transaction
-	var block *Block
 
+	if len(from) == 0 || len(from) != len(to) || len(from) != len(amount) {
+		return nil, fmt.Errorf("mismatched transfer arguments: %d from, %d to, %d amount", len(from), len(to), len(amount))
+	}
 	for index, address := range from {
-		value, _ := strconv.Atoi(amount[index])
-		tx := NewSimpleTransaciton(address, to[index], value, blockchain, txs)
+		value, err := ParsePositiveAmount(amount[index])
+		if err != nil {
+			return nil, err
+		}
+		tx, err := NewSimpleTransaciton(address, to[index], value, selector, blockchain, txs)
+		if err != nil {
+			return nil, err
+		}
+		// 必须在后续交易引用该交易之前添加，否则交易哈希会改变
+		if index == 0 && len(data) > 0 {
+			if err := tx.AddDataOutput(data); err != nil {
+				return nil, fmt.Errorf("add data output failed: %v", err)
+			}
+		}
 		txs = append(txs, tx)
 	}
 
+	return blockchain.MineBlock(txs), nil
+}
+
+// 提交原始交易，验证通过后打包成新区块，返回新区块
+func (blockchain *BlockChain) SendRawTransaction(tx *Transaction) (*Block, error) {
+	if err := blockchain.VerifyTransaction(tx, []*Transaction{}); err != nil {
+		return nil, err
+	}
+	return blockchain.MineBlock([]*Transaction{tx}), nil
+}
+
+// 一对多转账挖矿
+// 单个源地址向多个目标地址转账，只生成一笔交易，返回打包的新区块
+func (blockchain *BlockChain) MineMultiOutputBlock(from string, outputs map[string]Amount, selector CoinSelector, data []byte) (*Block, error) {
+	tx, err := NewMultiOutputTransaction(from, outputs, selector, blockchain, []*Transaction{})
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := tx.AddDataOutput(data); err != nil {
+			return nil, fmt.Errorf("add data output failed: %v", err)
+		}
+	}
+	return blockchain.MineBlock([]*Transaction{tx}), nil
+}
+
+// 将交易列表打包成新区块并写入数据库
+func (blockchain *BlockChain) MineBlock(txs []*Transaction) *Block {
+	var block *Block
+
 	// 从数据库中获取最新一个区块
 	blockchain.DB.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(blockTableName))
@@ -207,17 +345,25 @@ func (blockchain *BlockChain) MineNewBlock(from, to, amount []string) {
 		if b != nil {
 			err := b.Put(block.Hash, block.Serialize())
 			if err != nil {
-				log.Fatalf("update the new block to db failed %v\n", err)
+				chainLog.Fatalf("update the new block to db failed %v\n", err)
 			}
 
 			err = b.Put([]byte("l"), block.Hash)
 			if err != nil {
-				log.Fatalf("update the latest block hash to db failed %v\n", err)
+				chainLog.Fatalf("update the latest block hash to db failed %v\n", err)
+			}
+			if err := updateAddressIndex(tx, block.Hash); err != nil {
+				chainLog.Fatalf("update the address index failed %v\n", err)
+			}
+			if err := updateHeightIndex(tx, block.Hash); err != nil {
+				chainLog.Fatalf("update the height index failed %v\n", err)
 			}
 			blockchain.Tip = block.Hash
 		}
 		return nil
 	})
+	blockchain.publish(&Event{Type: EventBlockConnected, Block: block})
+	return block
 }
 
 // 查找指定地址的所有UTXO集合
@@ -225,14 +371,12 @@ func (blockchain *BlockChain) MineNewBlock(from, to, amount []string) {
 1. 遍历查找每一个区块中的每一个交易
 2. 查找每一个交易中的每一个输出
 3. 查找每一个交易中是否满足下列条件
-	1. 属于传入的地址
+	1. 属于传入的地址(数据输出不属于任何地址，不会出现在UTXO集合中)
 	2. 是否未被花费
 */
 func (blockchain *BlockChain) UnUTXOs(address string, txs []*Transaction) []*UTXO {
 	var unUTXOs []*UTXO
 	spentTxOutputs := blockchain.SpentOutputs(address)
-	// 遍历
-	bcit := blockchain.Iterator()
 	// 缓存迭代
 	for _, tx := range txs {
 		if !tx.IsCoinbaseTransaction() {
@@ -263,26 +407,25 @@ func (blockchain *BlockChain) UnUTXOs(address string, txs []*Transaction) []*UTX
 								}
 							}
 							if isSpentUTXO == false {
-								utxo := &UTXO{tx.TxHash, index, vout}
+								utxo := &UTXO{tx.TxHash, index, vout, 0}
 								unUTXOs = append(unUTXOs, utxo)
 							}
 						}
 					}
 					if isUtxoTx == false {
-						utxo := &UTXO{tx.TxHash, index, vout}
+						utxo := &UTXO{tx.TxHash, index, vout, 0}
 						unUTXOs = append(unUTXOs, utxo)
 					}
 				} else {
-					utxo := &UTXO{tx.TxHash, index, vout}
+					utxo := &UTXO{tx.TxHash, index, vout, 0}
 					unUTXOs = append(unUTXOs, utxo)
 				}
 			}
 		}
 	}
 
-	// 数据库迭代
-	for {
-		block := bcit.Next()
+	// 数据库迭代，从最新区块到创世区块
+	err := blockchain.ForEachBlock(blockchain.Height(), 1, func(block *Block) error {
 		for _, tx := range block.Txs {
 		work:
 			for index, vout := range tx.Vouts {
@@ -301,20 +444,18 @@ func (blockchain *BlockChain) UnUTXOs(address string, txs []*Transaction) []*UTX
 
 						}
 						if isSpentOutput == false {
-							unUTXOs = append(unUTXOs, &UTXO{tx.TxHash, index, vout})
+							unUTXOs = append(unUTXOs, &UTXO{tx.TxHash, index, vout, block.Height})
 						}
 					} else {
-						unUTXOs = append(unUTXOs, &UTXO{tx.TxHash, index, vout})
+						unUTXOs = append(unUTXOs, &UTXO{tx.TxHash, index, vout, block.Height})
 					}
 				}
 			}
 		}
-
-		var hashInt big.Int
-		hashInt.SetBytes(block.PrevBlockHash)
-		if hashInt.Cmp(big.NewInt(0)) == 0 {
-			break
-		}
+		return nil
+	})
+	if err != nil {
+		chainLog.Panicf("iterate the blockchain failed! %v\n", err)
 	}
 
 	return unUTXOs
@@ -323,9 +464,7 @@ func (blockchain *BlockChain) UnUTXOs(address string, txs []*Transaction) []*UTX
 // 获取指定地址上的所有"已花费"的输出
 func (blockchain *BlockChain) SpentOutputs(address string) map[string][]int {
 	spentOutputs := make(map[string][]int)
-	bcit := blockchain.Iterator()
-	for {
-		block := bcit.Next()
+	err := blockchain.ForEachBlock(blockchain.Height(), 1, func(block *Block) error {
 		for _, tx := range block.Txs {
 			if !tx.IsCoinbaseTransaction() {
 				for _, in := range tx.Vins {
@@ -336,49 +475,285 @@ func (blockchain *BlockChain) SpentOutputs(address string) map[string][]int {
 				}
 			}
 		}
-
-		var hashInt big.Int
-		hashInt.SetBytes(block.PrevBlockHash)
-		if hashInt.Cmp(big.NewInt(0)) == 0 {
-			break
-		}
+		return nil
+	})
+	if err != nil {
+		chainLog.Panicf("iterate the blockchain failed! %v\n", err)
 	}
 	return spentOutputs
 }
 
 // 查询余额
-func (blockchain *BlockChain) getBalance(address string) int {
-	var amount int
+func (blockchain *BlockChain) getBalance(address string) Amount {
+	var amount Amount
 	utxos := blockchain.UnUTXOs(address, []*Transaction{})
 	for _, utxo := range utxos {
-		amount += utxo.Output.Value
+		var err error
+		if amount, err = amount.Add(utxo.Output.Value); err != nil {
+			chainLog.Panicf("sum of balance failed! %v\n", err)
+		}
 	}
 	return amount
 }
 
-// 查找指定地址的可用UTXO，超过amount就中断查找
+// 查询指定地址的余额：height大于0时为该高度的余额，否则t大于0时为该时间的余额，否则为当前余额
+func (blockchain *BlockChain) BalanceAt(address string, height, t int64) (Amount, error) {
+	switch {
+	case height > 0:
+		return blockchain.BalanceAtHeight(address, height)
+	case t > 0:
+		return blockchain.BalanceAtTime(address, t)
+	}
+	return blockchain.getBalance(address), nil
+}
+
+// 查询指定地址在主链上高度为height的区块(包括该区块中的交易)之后的余额
+// 由地址索引累加该高度及之前的交易引起的余额变化，结果与当时的UTXO集合中该地址的输出之和相同
+func (blockchain *BlockChain) BalanceAtHeight(address string, height int64) (Amount, error) {
+	if tipHeight := blockchain.Height(); height < 1 || height > tipHeight {
+		return 0, fmt.Errorf("height [%d] out of range [1, %d]", height, tipHeight)
+	}
+	entries, err := blockchain.AddressIndex(address)
+	if err != nil {
+		return 0, err
+	}
+	var balance Amount
+	for _, entry := range entries {
+		if entry.Height > height {
+			break
+		}
+		balance += entry.Delta()
+	}
+	return balance, nil
+}
+
+// 查询指定地址在时间t(秒)的余额，即主链上时间戳不晚于t的最高区块之后的余额
+// 没有这样的区块时余额为0
+func (blockchain *BlockChain) BalanceAtTime(address string, t int64) (Amount, error) {
+	height := blockchain.HeightAtTime(t)
+	if height == 0 {
+		return 0, nil
+	}
+	return blockchain.BalanceAtHeight(address, height)
+}
+
+// 主链上时间戳不晚于t(秒)的最高区块的高度，没有这样的区块时返回0
+func (blockchain *BlockChain) HeightAtTime(t int64) int64 {
+	var height int64
+	err := blockchain.ForEachBlock(blockchain.Height(), 1, func(block *Block) error {
+		if block.TimeStamp <= t {
+			height = block.Height
+			return ErrStopIteration
+		}
+		return nil
+	})
+	if err != nil {
+		chainLog.Panicf("iterate the blockchain failed! %v\n", err)
+	}
+	return height
+}
+
+// 查询主链上指定地址确认数在[minConf, maxConf]之间的UTXO
+func (blockchain *BlockChain) ListUnspent(address string, minConf, maxConf int64) []*UTXO {
+	var utxos []*UTXO
+	tipHeight := blockchain.Height()
+	for _, utxo := range blockchain.UnUTXOs(address, nil) {
+		if confirmations := utxo.Confirmations(tipHeight); confirmations >= minConf && confirmations <= maxConf {
+			utxos = append(utxos, utxo)
+		}
+	}
+	return utxos
+}
+
+// 查找指定地址的可用UTXO，由选币策略决定使用哪些UTXO
 // 更新当前数据库中指定地址的UTXO数量
 // txs:缓存中的交易列表
-func (blockchain *BlockChain) FindSpendableUTXO(from string, amount int, txs []*Transaction) (int, map[string][]int) {
+// selector:选币策略，为nil时使用默认策略
+// 余额不足或选币策略找不到合适的UTXO时返回错误
+func (blockchain *BlockChain) FindSpendableUTXO(from string, amount Amount, txs []*Transaction, selector CoinSelector) (Amount, map[string][]int, error) {
 	spendableUTXO := make(map[string][]int)
 
-	var value int
+	if selector == nil {
+		selector, _ = NewCoinSelector(defaultCoinSelector)
+	}
 	utxos := blockchain.UnUTXOs(from, txs)
+	selected, err := selector.Select(utxos, amount)
+	if err != nil {
+		var balance Amount
+		for _, utxo := range utxos {
+			balance += utxo.Output.Value
+		}
+		if balance < amount {
+			return 0, nil, fmt.Errorf("insufficient funds in [%s]: balance [%s], amount [%s]", from, balance, amount)
+		}
+		return 0, nil, fmt.Errorf("select utxos of [%s] for amount [%s] failed: %v", from, amount, err)
+	}
 
-	// 遍历UTXO集合
-	for _, utxo := range utxos {
-		value += utxo.Output.Value
+	var value Amount
+	for _, utxo := range selected {
+		if value, err = value.Add(utxo.Output.Value); err != nil {
+			return 0, nil, fmt.Errorf("sum of spendable utxos failed: %v", err)
+		}
 		hash := hex.EncodeToString(utxo.TxHash)
 		spendableUTXO[hash] = append(spendableUTXO[hash], utxo.Index)
-		if value >= amount {
-			break
+	}
+
+	return value, spendableUTXO, nil
+}
+
+// 验证一笔普通交易
+// 1. 交易哈希与内容一致
+// 2. 所有输入均已签名，且引用的输出属于签名地址并且未被花费
+// 3. 金额在合法范围内，输入总额不小于输出总额
+// txs:缓存中的交易列表
+func (blockchain *BlockChain) VerifyTransaction(tx *Transaction, txs []*Transaction) error {
+	if len(tx.Vins) == 0 || len(tx.Vouts) == 0 {
+		return errors.New("transaction has no inputs or outputs")
+	}
+	if tx.IsCoinbaseTransaction() {
+		return errors.New("coinbase transaction can not be submitted")
+	}
+	check := &Transaction{nil, tx.Vins, tx.Vouts}
+	check.HashTransaction()
+	if !bytes.Equal(check.TxHash, tx.TxHash) {
+		return fmt.Errorf("transaction hash mismatch [%x]", tx.TxHash)
+	}
+
+	var inputValue Amount
+	used := make(map[string]bool)
+	// 每个签名地址的UTXO只查询一次
+	utxosByAddress := make(map[string][]*UTXO)
+	for _, in := range tx.Vins {
+		if in.ScriptSig == "" {
+			return fmt.Errorf("input [%x:%d] is not signed", in.TxHash, in.Vout)
+		}
+		key := fmt.Sprintf("%x:%d", in.TxHash, in.Vout)
+		if used[key] {
+			return fmt.Errorf("input [%s] is spent twice", key)
+		}
+		used[key] = true
+
+		utxos, ok := utxosByAddress[in.ScriptSig]
+		if !ok {
+			utxos = blockchain.UnUTXOs(in.ScriptSig, txs)
+			utxosByAddress[in.ScriptSig] = utxos
+		}
+		var found bool
+		for _, utxo := range utxos {
+			if bytes.Equal(utxo.TxHash, in.TxHash) && utxo.Index == in.Vout {
+				var err error
+				if inputValue, err = inputValue.Add(utxo.Output.Value); err != nil {
+					return fmt.Errorf("sum of inputs: %v", err)
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("input [%s] is not an unspent output of [%s]", key, in.ScriptSig)
+		}
+	}
+
+	var outputValue Amount
+	var dataOutputs int
+	for index, out := range tx.Vouts {
+		// 数据输出：金额为0，不属于任何地址，大小受限，每笔交易最多一个
+		if out.IsDataOutput() {
+			dataOutputs++
+			if dataOutputs > 1 {
+				return errors.New("transaction has more than one data output")
+			}
+			if out.Value != 0 || out.ScriptPubkey != "" || len(out.Data) > MaxDataOutputSize {
+				return fmt.Errorf("output %d is an invalid data output", index)
+			}
+			continue
+		}
+		if out.Value <= 0 || !out.Value.IsValid() {
+			return fmt.Errorf("output %d has invalid value [%s]", index, out.Value)
 		}
+		var err error
+		if outputValue, err = outputValue.Add(out.Value); err != nil {
+			return fmt.Errorf("sum of outputs: %v", err)
+		}
+	}
+	if inputValue < outputValue {
+		return fmt.Errorf("outputs [%s] exceed inputs [%s]", outputValue, inputValue)
+	}
+	return nil
+}
+
+// 根据交易哈希查找交易及其所在区块
+func (blockchain *BlockChain) FindTransaction(txHash []byte) (*Transaction, *Block) {
+	var found *Transaction
+	var foundBlock *Block
+	err := blockchain.ForEachBlock(blockchain.Height(), 1, func(block *Block) error {
+		for _, tx := range block.Txs {
+			if bytes.Equal(tx.TxHash, txHash) {
+				found, foundBlock = tx, block
+				return ErrStopIteration
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		chainLog.Panicf("iterate the blockchain failed! %v\n", err)
 	}
+	return found, foundBlock
+}
+
+// 与地址相关的交易
+type AddressTx struct {
+	Tx       *Transaction
+	Block    *Block
+	Received Amount // 该交易中转入该地址的金额
+	Sent     Amount // 该交易花费的该地址的金额
+}
 
-	if value < amount {
-		fmt.Printf("地址 [%s] 余额不足，当前余额 [%d]，转账金额 [%d]\n", from, value, amount)
-		os.Exit(1)
+// 查询主链上与指定地址相关的交易，按高度从低到高排列
+// 由地址索引(见AddrIndex.go)找到交易所在的区块
+func (blockchain *BlockChain) AddressHistory(address string) []*AddressTx {
+	entries, err := blockchain.AddressIndex(address)
+	if err != nil {
+		chainLog.Panicf("query the address index failed! %v\n", err)
 	}
+	var history []*AddressTx
+	var block *Block
+	for _, entry := range entries {
+		if block == nil || !bytes.Equal(block.Hash, entry.BlockHash) {
+			if block = blockchain.GetBlock(entry.BlockHash); block == nil {
+				chainLog.Panicf("block [%x] in the address index not found!\n", entry.BlockHash)
+			}
+		}
+		history = append(history, &AddressTx{
+			Tx:       block.Txs[entry.TxIndex],
+			Block:    block,
+			Received: entry.Received,
+			Sent:     entry.Sent,
+		})
+	}
+	return history
+}
 
-	return value, spendableUTXO
+// 主链上未花费输出的数量(不含数据输出)
+func (blockchain *BlockChain) UTXOCount() int {
+	unspent := make(map[string]bool)
+	err := blockchain.ForEachBlock(1, 0, func(block *Block) error {
+		for _, tx := range block.Txs {
+			if !tx.IsCoinbaseTransaction() {
+				for _, in := range tx.Vins {
+					delete(unspent, fmt.Sprintf("%x:%d", in.TxHash, in.Vout))
+				}
+			}
+			for index, out := range tx.Vouts {
+				if !out.IsDataOutput() {
+					unspent[fmt.Sprintf("%x:%d", tx.TxHash, index)] = true
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		chainLog.Panicf("iterate the blockchain failed! %v\n", err)
+	}
+	return len(unspent)
 }
@@ -0,0 +1,111 @@
+package BLC
+
+import (
+	"testing"
+	"time"
+)
+
+// 在临时数据目录中创建区块链，创世区块奖励属于address，测试结束时关闭数据库
+func newTestChain(t *testing.T, address string) *BlockChain {
+	t.Helper()
+	SetDataDir(t.TempDir())
+	t.Cleanup(func() { SetDataDir(".") })
+	blockchain := CreateBlockChainWithGenesisBlock(address)
+	t.Cleanup(func() { blockchain.DB.Close() })
+	return blockchain
+}
+
+// 在parent所在的链上构造from向to转账amount的交易，并打包成parent的子区块(不写入数据库)
+func newTestBlock(t *testing.T, blockchain *BlockChain, parent *Block, from, to string, amount Amount) *Block {
+	t.Helper()
+	view := &BlockChain{DB: blockchain.DB, Tip: parent.Hash}
+	tx, err := NewSimpleTransaciton(from, to, amount, LargestFirstSelector{}, view, nil)
+	if err != nil {
+		t.Fatalf("create transaction from [%s] to [%s] failed: %v", from, to, err)
+	}
+	return NewBlock(parent.Height+1, parent.Hash, []*Transaction{tx})
+}
+
+// 接收区块，出错时结束测试，返回最新区块是否发生变化
+func acceptTestBlock(t *testing.T, blockchain *BlockChain, block *Block) bool {
+	t.Helper()
+	tipChanged, err := blockchain.AcceptBlock(block)
+	if err != nil {
+		t.Fatalf("AcceptBlock(%d) error: %v", block.Height, err)
+	}
+	return tipChanged
+}
+
+func TestBalanceAtHeight(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+	for _, transfer := range []struct {
+		from, to string
+		amount   Amount
+	}{
+		{"alice", "bob", 3 * CoinUnit},
+		{"bob", "carol", CoinUnit},
+		{"alice", "carol", 2 * CoinUnit},
+	} {
+		acceptTestBlock(t, blockchain, newTestBlock(t, blockchain, blockchain.TipBlock(), transfer.from, transfer.to, transfer.amount))
+	}
+
+	want := map[string][]Amount{
+		"alice": {10 * CoinUnit, 7 * CoinUnit, 7 * CoinUnit, 5 * CoinUnit},
+		"bob":   {0, 3 * CoinUnit, 2 * CoinUnit, 2 * CoinUnit},
+		"carol": {0, 0, CoinUnit, 3 * CoinUnit},
+		"dave":  {0, 0, 0, 0},
+	}
+	tip := blockchain.Height()
+	for address, balances := range want {
+		for i, balance := range balances {
+			height := int64(i + 1)
+			got, err := blockchain.BalanceAtHeight(address, height)
+			if err != nil {
+				t.Fatalf("BalanceAtHeight(%s, %d) error: %v", address, height, err)
+			}
+			if got != balance {
+				t.Errorf("BalanceAtHeight(%s, %d) = %s, want %s", address, height, got, balance)
+			}
+		}
+		got, err := blockchain.BalanceAtHeight(address, tip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current := blockchain.getBalance(address); got != current {
+			t.Errorf("BalanceAtHeight(%s, tip) = %s, getBalance = %s", address, got, current)
+		}
+		if got, err := blockchain.BalanceAt(address, 0, 0); err != nil || got != blockchain.getBalance(address) {
+			t.Errorf("BalanceAt(%s, 0, 0) = %s, %v", address, got, err)
+		}
+	}
+
+	for _, height := range []int64{-1, 0, tip + 1} {
+		if _, err := blockchain.BalanceAtHeight("alice", height); err == nil {
+			t.Errorf("BalanceAtHeight(alice, %d) succeeded", height)
+		}
+	}
+}
+
+func TestBalanceAtTime(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+	genesis := blockchain.TipBlock()
+	acceptTestBlock(t, blockchain, newTestBlock(t, blockchain, genesis, "alice", "bob", CoinUnit))
+	tip := blockchain.TipBlock()
+
+	if height := blockchain.HeightAtTime(genesis.TimeStamp - 1); height != 0 {
+		t.Fatalf("HeightAtTime before genesis = %d, want 0", height)
+	}
+	if height := blockchain.HeightAtTime(tip.TimeStamp); height != tip.Height {
+		t.Fatalf("HeightAtTime(tip time) = %d, want %d", height, tip.Height)
+	}
+	if balance, err := blockchain.BalanceAtTime("alice", genesis.TimeStamp-1); err != nil || balance != 0 {
+		t.Fatalf("BalanceAtTime before genesis = %s, %v, want 0", balance, err)
+	}
+	now := time.Now().Unix()
+	if balance, err := blockchain.BalanceAt("alice", 0, now); err != nil || balance != 9*CoinUnit {
+		t.Fatalf("BalanceAt(alice, 0, now) = %s, %v, want 9", balance, err)
+	}
+	if balance, err := blockchain.BalanceAt("alice", 1, now); err != nil || balance != coinbaseReward {
+		t.Fatalf("BalanceAt(alice, 1, now) = %s, %v, want 10", balance, err)
+	}
+}
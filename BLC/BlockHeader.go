@@ -0,0 +1,107 @@
+package BLC
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// 区块头管理文件
+// 区块头不包含交易列表，只包含交易列表的哈希，用于先同步区块头再下载区块的同步方式
+
+// 区块头结构
+type BlockHeader struct {
+	TimeStamp     int64  // 区块时间戳
+	Hash          []byte // 当前区块哈希
+	PrevBlockHash []byte // 父区块哈希
+	Height        int64  // 区块高度
+	Nonce         int64  // 运行pow是的修改值
+	TxRoot        []byte // 交易列表的哈希
+}
+
+// 获取区块的区块头
+func (block *Block) Header() *BlockHeader {
+	return &BlockHeader{
+		TimeStamp:     block.TimeStamp,
+		Hash:          block.Hash,
+		PrevBlockHash: block.PrevBlockHash,
+		Height:        block.Height,
+		Nonce:         block.Nonce,
+		TxRoot:        block.HashTransaction(),
+	}
+}
+
+// 验证区块头的工作量证明
+func (header *BlockHeader) ValidatePoW() bool {
+	var hashInt big.Int
+	hash := sha256.Sum256(powData(header.TimeStamp, header.Height, header.PrevBlockHash, header.TxRoot, header.Nonce))
+	hashInt.SetBytes(hash[:])
+	target := NewProofOfWork(nil).target
+	return bytes.Equal(hash[:], header.Hash) && target.Cmp(&hashInt) == 1
+}
+
+// 区块头序列化
+func (header *BlockHeader) Serialize() []byte {
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.BigEndian, header.TimeStamp)
+	binary.Write(&buffer, binary.BigEndian, header.Height)
+	binary.Write(&buffer, binary.BigEndian, header.Nonce)
+	writeVarBytes(&buffer, header.Hash)
+	writeVarBytes(&buffer, header.PrevBlockHash)
+	writeVarBytes(&buffer, header.TxRoot)
+	return buffer.Bytes()
+}
+
+// 区块头反序列化
+func readBlockHeader(reader *bytes.Reader) (*BlockHeader, error) {
+	header := &BlockHeader{}
+	for _, field := range []*int64{&header.TimeStamp, &header.Height, &header.Nonce} {
+		if err := binary.Read(reader, binary.BigEndian, field); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if header.Hash, err = readVarBytes(reader); err != nil {
+		return nil, err
+	}
+	if header.PrevBlockHash, err = readVarBytes(reader); err != nil {
+		return nil, err
+	}
+	if header.TxRoot, err = readVarBytes(reader); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// 区块头列表序列化，用于headers消息
+func serializeHeaders(headers []*BlockHeader) []byte {
+	var buffer bytes.Buffer
+	writeUvarint(&buffer, uint64(len(headers)))
+	for _, header := range headers {
+		buffer.Write(header.Serialize())
+	}
+	return buffer.Bytes()
+}
+
+// 区块头列表反序列化
+func deserializeHeaders(payload []byte) ([]*BlockHeader, error) {
+	reader := bytes.NewReader(payload)
+	count, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+	if count > maxHeadersPerMsg {
+		return nil, fmt.Errorf("too many headers [%d]", count)
+	}
+	var headers []*BlockHeader
+	for i := uint64(0); i < count; i++ {
+		header, err := readBlockHeader(reader)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
@@ -1,51 +1,200 @@
 package BLC
 
 import (
+	"encoding/hex"
 	"flag"
 	"fmt"
-	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
 // 对blockchain的命令行操作进行管理
 
 // client对象
 type CLI struct {
+	rpc  *RPCClient // 不为空时通过RPC访问运行中的节点
+	json bool       // 以JSON格式输出命令结果
 }
 
 // 用法展示
 func PrintUsage() {
-	fmt.Println("Usage:")
+	fmt.Println("Usage: bc [-datadir DIR] [-json] [-loglevel LEVEL] [-logformat text|json] [-logfile FILE] [-rpcconnect HOST:PORT [-rpcuser USER -rpcpassword PASSWORD]] COMMAND [ARGS]")
+	fmt.Printf("\t-datadir DIR -- 数据目录(默认为当前目录)\n")
+	fmt.Printf("\t-json -- 以JSON格式输出命令结果(哈希为hex字符串，金额为数字)\n")
+	fmt.Printf("\t-loglevel LEVEL -- 日志级别：debug|info(默认)|warn|error，可按子系统指定，例如warn,NET=debug(子系统：%s)\n", strings.Join(LogSubsystems(), "|"))
+	fmt.Printf("\t-logformat FORMAT -- 日志格式：text(默认)|json\n")
+	fmt.Printf("\t-logfile FILE -- 日志写入文件(默认输出到标准错误)，超过-logmaxsize MB(默认%d)时轮转，保留-logmaxfiles个历史文件(默认%d)\n", defaultLogMaxSize/1024/1024, defaultLogMaxFiles)
+	fmt.Printf("\t-rpcconnect HOST:PORT -- 通过RPC访问运行中的节点(getbalance、listunspent、gethistory、send、sendrawtransaction、printchain、gettransaction及封禁命令)，不打开本地数据库\n")
+	fmt.Printf("\t-rpcuser USER -rpcpassword PASSWORD -- RPC认证的用户名与密码，未指定时读取数据目录中的cookie文件\n")
 	// 初始化
 	fmt.Printf("\tcreateblockchain --address Address -- 创建区块链\n")
 	// 添加区块
 	// fmt.Printf("\taddblock --txs Transaction -- 添加区块\n")
 	// 打印完整的区块信息
-	fmt.Printf("\tprintchain -- 输出区块链信息\n")
+	fmt.Printf("\tprintchain [-from N] [-to N] [-limit N] [-reverse] [-headers-only] [-address ADDR] [-since TIMESTAMP] -- 输出区块链信息\n")
+	fmt.Printf("\t\t-from N -to N -- 高度范围\n")
+	fmt.Printf("\t\t-limit N -- 最多输出的区块数量\n")
+	fmt.Printf("\t\t-reverse -- 从创世区块开始输出(默认从最新区块开始)\n")
+	fmt.Printf("\t\t-headers-only -- 只输出区块头\n")
+	fmt.Printf("\t\t-address ADDR -- 只输出交易输入或输出涉及该地址的区块\n")
+	fmt.Printf("\t\t-since TIMESTAMP -- 只输出时间戳不早于TIMESTAMP(秒)的区块\n")
 	// 通过命令转账
 	fmt.Printf("\tsend -from FROM -to TO -amount AMOUNT -- 发起转账\n")
 	fmt.Printf("\t\t-from FROM -- 转账源地址\n")
 	fmt.Printf("\t\t-to TO -- 转账目标地址\n")
-	fmt.Printf("\t\t-amount AMOUNT -- 转账金额\n")
-	fmt.Printf("\tgetbalance -address FROM -- 查询指定地址的余额\n")
+	fmt.Printf("\t\t-amount AMOUNT -- 转账金额，支持小数，例如1.25(最多8位小数)\n")
+	fmt.Printf("\t\t-coinselect STRATEGY -- 选币策略：largest(默认)|smallest|oldest|bnb\n")
+	fmt.Printf("\t\t-data DATA -- 附加不可花费的数据输出，以0x开头按hex解析，否则按文本处理(最多%d字节)\n", MaxDataOutputSize)
+	fmt.Printf("\tsend -from FROM -to '{\"TO1\":AMOUNT1,\"TO2\":AMOUNT2}' -- 单个源地址向多个目标地址转账(一笔交易)\n")
+	// 原始交易
+	fmt.Printf("\tcreaterawtransaction -inputs '[{\"txid\":TXID,\"vout\":N}]' -outputs '{\"TO\":AMOUNT}' -- 创建未签名的原始交易(hex)\n")
+	fmt.Printf("\t\t-data DATA -- 附加数据输出\n")
+	fmt.Printf("\tdecoderawtransaction -hex HEX -- 解析原始交易\n")
+	fmt.Printf("\tsignrawtransaction -hex HEX -address FROM -- 使用指定地址对原始交易签名\n")
+	fmt.Printf("\tsendrawtransaction -hex HEX [-node HOST:PORT] -- 验证并提交原始交易(打包成新区块，指定-node或-rpcconnect时提交到运行中的节点的交易池)\n")
+	fmt.Printf("\tgettransaction -txid TXID -- 查询指定交易\n")
+	fmt.Printf("\tgetbalance -address FROM [-height N | -time TIMESTAMP] -- 查询指定地址的余额\n")
+	fmt.Printf("\t\t-height N -- 查询主链上高度N的区块之后的余额\n")
+	fmt.Printf("\t\t-time TIMESTAMP -- 查询时间TIMESTAMP(秒)的余额，即时间戳不晚于TIMESTAMP的最高区块之后的余额\n")
+	fmt.Printf("\tlistunspent -address ADDR [-minconf N] [-maxconf M] [-sort age|value] [-reverse] -- 列出指定地址的未花费输出\n")
+	fmt.Printf("\t\t-minconf N -maxconf M -- 只列出确认数在N到M之间的输出(默认%d到%d)\n", defaultMinConf, defaultMaxConf)
+	fmt.Printf("\t\t-sort age|value -- 按区块高度(最早的在前，默认)或金额(最大的在前)排序，-reverse反向排序\n")
+	fmt.Printf("\tgethistory -address ADDR -- 列出指定地址的交易历史(高度、时间、余额变化与交易后的余额)\n")
+	fmt.Printf("\tstartnode -port PORT [-seed HOST:PORT] [-mine] [-outbound N] [-rpc [HOST]:PORT] [-http [HOST]:PORT] -- 启动节点\n")
+	fmt.Printf("\t\t-port PORT -- 监听端口\n")
+	fmt.Printf("\t\t-seed HOST:PORT -- 启动时连接的节点\n")
+	fmt.Printf("\t\t-mine -- 将交易池中的交易打包成区块\n")
+	fmt.Printf("\t\t-outbound N -- 维持的主动连接数量(默认%d)\n", defaultTargetOutbound)
+	fmt.Printf("\t\t-rpc [HOST]:PORT -- 启动JSON-RPC服务，未指定主机时只监听本机\n")
+	fmt.Printf("\t\t-rpcuser USER -rpcpassword PASSWORD -- RPC认证的用户名与密码，未指定时使用数据目录中的cookie文件\n")
+	fmt.Printf("\t\t-http [HOST]:PORT -- 启动只读REST接口与区块浏览器，未指定主机时只监听本机\n")
+	fmt.Printf("\t\t-blocknotify CMD -- 区块接入主链时执行的命令，%%s替换为区块哈希\n")
+	fmt.Printf("\t\t-walletnotify CMD -walletaddress ADDR1,ADDR2 -- 涉及指定地址的交易进入交易池或被打包时执行的命令，%%s替换为交易哈希\n")
+	fmt.Printf("\tlistbanned -- 列出被封禁的节点\n")
+	fmt.Printf("\tsetban -addr ADDR [-bantime SECONDS] [-remove] -- 封禁或解除封禁节点(主机或主机:端口)\n")
+	fmt.Printf("\tclearbanned -- 清空封禁列表\n")
 	fmt.Printf("\t查询余额参数说明\n")
 	fmt.Printf("\t\t-address --查询余额的地址\n")
 }
 
-// 查询余额
-func (cli *CLI) getBalance(from string) {
+// 查询余额，height或t大于0时查询主链上该高度或时间的余额
+func (cli *CLI) getBalance(from string, height, t int64) {
+	if cli.rpc != nil {
+		cli.clientGetBalance(from, height, t)
+		return
+	}
 	if !dbExist() {
 		fmt.Printf("数据库不存在...")
 		os.Exit(1)
 	}
 	blockchain := BlockchainObject()
 	defer blockchain.DB.Close()
-	amount := blockchain.getBalance(from)
-	fmt.Printf("\t地址 [%s] 的余额：[%d]\n", from, amount)
+	amount, err := blockchain.BalanceAt(from, height, t)
+	if err != nil {
+		fmt.Printf("查询余额失败：%v\n", err)
+		os.Exit(1)
+	}
+	cli.printBalance(&BalanceResult{from, amount, height, t})
+}
+
+// 输出余额查询结果
+func (cli *CLI) printBalance(result *BalanceResult) {
+	cli.output(result, func() {
+		switch {
+		case result.Height > 0:
+			fmt.Printf("\t地址 [%s] 在高度 [%d] 的余额：[%s]\n", result.Address, result.Height, result.Balance)
+		case result.Time > 0:
+			fmt.Printf("\t地址 [%s] 在时间 [%s] 的余额：[%s]\n", result.Address, time.Unix(result.Time, 0).Format("2006-01-02 15:04:05"), result.Balance)
+		default:
+			fmt.Printf("\t地址 [%s] 的余额：[%s]\n", result.Address, result.Balance)
+		}
+	})
+}
+
+// 列出指定地址的未花费输出
+func (cli *CLI) listUnspent(address string, minConf, maxConf int64, sortBy string, reverse bool) {
+	var utxos []*UTXO
+	var tipHeight int64
+	if cli.rpc != nil {
+		var results []*UTXOResult
+		cli.call("getblockcount", &tipHeight)
+		cli.call("listunspent", &results, address, minConf, maxConf)
+		for _, result := range results {
+			utxo, err := result.UTXO()
+			if err != nil {
+				fmt.Printf("解析未花费输出失败：%v\n", err)
+				os.Exit(1)
+			}
+			utxos = append(utxos, utxo)
+		}
+	} else {
+		if !dbExist() {
+			fmt.Printf("数据库不存在...")
+			os.Exit(1)
+		}
+		blockchain := BlockchainObject()
+		defer blockchain.DB.Close()
+		tipHeight = blockchain.Height()
+		utxos = blockchain.ListUnspent(address, minConf, maxConf)
+	}
+	if err := SortUTXOs(utxos, sortBy, reverse); err != nil {
+		fmt.Printf("排序方式无效：%v\n", err)
+		os.Exit(1)
+	}
+	results := []*UTXOResult{}
+	var total Amount
+	for _, utxo := range utxos {
+		results = append(results, NewUTXOResult(utxo, tipHeight))
+		var err error
+		if total, err = total.Add(utxo.Output.Value); err != nil {
+			cliLog.Panicf("sum of utxos failed! %v\n", err)
+		}
+	}
+	cli.output(results, func() {
+		fmt.Printf("\t地址 [%s] 的未花费输出：[%d] 个，合计 [%s]\n", address, len(results), total)
+		for _, result := range results {
+			fmt.Printf("\t\ttxid：%s vout：%d 金额：%s 高度：%d 确认数：%d\n", result.TxID, result.Vout, result.Amount, result.Height, result.Confirmations)
+		}
+	})
+}
+
+// 列出指定地址的交易历史，按交易在主链上的顺序排列
+func (cli *CLI) getHistory(address string) {
+	var results []*HistoryResult
+	if cli.rpc != nil {
+		cli.call("gethistory", &results, address)
+	} else {
+		if !dbExist() {
+			fmt.Printf("数据库不存在...")
+			os.Exit(1)
+		}
+		blockchain := BlockchainObject()
+		defer blockchain.DB.Close()
+		entries, err := blockchain.AddressIndex(address)
+		if err != nil {
+			fmt.Printf("查询地址索引失败：%v\n", err)
+			os.Exit(1)
+		}
+		results = NewHistoryResults(entries)
+	}
+	cli.output(results, func() {
+		fmt.Printf("\t地址 [%s] 的交易历史：[%d] 笔\n", address, len(results))
+		for _, result := range results {
+			fmt.Printf("\t\t高度：%d 时间：%s txid：%s 变化：%s 余额：%s\n", result.Height,
+				time.Unix(result.Time, 0).Format("2006-01-02 15:04:05"), result.TxID, result.Delta, result.Balance)
+		}
+	})
 }
 
-// 发起交易
-func (cli *CLI) send(from, to, amount []string) {
+// 发起交易，coinSelect为选币策略名称
+func (cli *CLI) send(from, to, amount []string, coinSelect string, data []byte) {
+	if cli.rpc != nil {
+		cli.clientSend(from, to, amount, coinSelect, data)
+		return
+	}
 	if !dbExist() {
 		fmt.Printf("数据库不存在...")
 		os.Exit(1)
@@ -56,12 +205,342 @@ func (cli *CLI) send(from, to, amount []string) {
 		fmt.Printf("交易参数输入有误，请检查一致性...\n")
 		os.Exit(1)
 	}
-	blockchain.MineNewBlock(from, to, amount)
+	for _, value := range amount {
+		if _, err := ParsePositiveAmount(value); err != nil {
+			fmt.Printf("转账金额 [%s] 无效：%v\n", value, err)
+			os.Exit(1)
+		}
+	}
+	selector, _ := NewCoinSelector(coinSelect)
+	block, err := blockchain.MineNewBlock(from, to, amount, selector, data)
+	if err != nil {
+		fmt.Printf("转账失败：%v\n", err)
+		os.Exit(1)
+	}
+	cli.printSendResult(block)
+}
+
+// 发起一对多转账
+func (cli *CLI) sendMany(from string, outputs map[string]Amount, coinSelect string, data []byte) {
+	if len(outputs) == 0 {
+		fmt.Printf("目标地址不能为空...\n")
+		os.Exit(1)
+	}
+	for to, value := range outputs {
+		if value <= 0 {
+			fmt.Printf("转账金额必须大于0，目标地址 [%s]，金额 [%s]\n", to, value)
+			os.Exit(1)
+		}
+	}
+	if cli.rpc != nil {
+		cli.clientSendMany(from, outputs, coinSelect, data)
+		return
+	}
+	if !dbExist() {
+		fmt.Printf("数据库不存在...")
+		os.Exit(1)
+	}
+	blockchain := BlockchainObject()
+	defer blockchain.DB.Close()
+	selector, _ := NewCoinSelector(coinSelect)
+	block, err := blockchain.MineMultiOutputBlock(from, outputs, selector, data)
+	if err != nil {
+		fmt.Printf("转账失败：%v\n", err)
+		os.Exit(1)
+	}
+	cli.printSendResult(block)
+}
+
+// 输出转账打包的区块中的交易
+func (cli *CLI) printSendResult(block *Block) {
+	result := NewSendResult(block)
+	cli.output(result, func() {
+		for _, txid := range result.TxIDs {
+			fmt.Printf("\ttxid：%s\n", txid)
+		}
+		fmt.Printf("\t区块：%s，高度：%d\n", result.BlockHash, result.Height)
+	})
+}
+
+// 创建未签名的原始交易
+func (cli *CLI) createRawTransaction(inputs []RawTxInput, outputs map[string]Amount, data []byte) {
+	tx, err := NewRawTransaction(inputs, outputs)
+	if err != nil {
+		fmt.Printf("创建原始交易失败：%v\n", err)
+		os.Exit(1)
+	}
+	if len(data) > 0 {
+		if err := tx.AddDataOutput(data); err != nil {
+			fmt.Printf("添加数据输出失败：%v\n", err)
+			os.Exit(1)
+		}
+	}
+	rawHex := EncodeRawTransaction(tx)
+	cli.output(&RawTxResult{Hex: rawHex}, func() {
+		fmt.Println(rawHex)
+	})
+}
+
+// 解析原始交易
+func (cli *CLI) decodeRawTransaction(rawHex string) {
+	tx, err := DecodeRawTransaction(rawHex)
+	if err != nil {
+		fmt.Printf("解析原始交易失败：%v\n", err)
+		os.Exit(1)
+	}
+	cli.output(&DecodedTxResult{NewTxResult(tx, nil, 0), tx.IsSigned()}, func() {
+		fmt.Printf("\t已签名：%v\n", tx.IsSigned())
+		tx.PrintTransaction()
+	})
+}
+
+// 对原始交易签名，无需访问数据库，可以在离线环境中执行
+func (cli *CLI) signRawTransaction(rawHex string, address string) {
+	tx, err := DecodeRawTransaction(rawHex)
+	if err != nil {
+		fmt.Printf("解析原始交易失败：%v\n", err)
+		os.Exit(1)
+	}
+	signed := tx.Sign(address)
+	rawHex = EncodeRawTransaction(tx)
+	cli.output(&RawTxResult{Hex: rawHex, Signed: &signed}, func() {
+		fmt.Printf("\t签名输入数量：%d\n", signed)
+		fmt.Println(rawHex)
+	})
+}
+
+// 验证并提交原始交易
+// node不为空时发送到运行中的节点，客户端模式下通过RPC提交到节点的交易池，否则直接打包成新区块
+func (cli *CLI) sendRawTransaction(rawHex string, node string) {
+	if node == "" && cli.rpc != nil {
+		cli.clientSendRawTransaction(rawHex)
+		return
+	}
+	tx, err := DecodeRawTransaction(rawHex)
+	if err != nil {
+		fmt.Printf("解析原始交易失败：%v\n", err)
+		os.Exit(1)
+	}
+	if node != "" {
+		if err := SendTransactionToNode(node, tx); err != nil {
+			fmt.Printf("发送交易失败：%v\n", err)
+			os.Exit(1)
+		}
+		cli.output(&SendResult{TxIDs: []string{hex.EncodeToString(tx.TxHash)}}, func() {
+			fmt.Printf("\ttxid：%x\n", tx.TxHash)
+		})
+		return
+	}
+	if !dbExist() {
+		fmt.Printf("数据库不存在...")
+		os.Exit(1)
+	}
+	blockchain := BlockchainObject()
+	defer blockchain.DB.Close()
+	block, err := blockchain.SendRawTransaction(tx)
+	if err != nil {
+		fmt.Printf("交易验证失败：%v\n", err)
+		os.Exit(1)
+	}
+	cli.printSendResult(block)
+}
+
+// 查询指定交易
+func (cli *CLI) getTransaction(txid string) {
+	if cli.rpc != nil {
+		cli.clientGetTransaction(txid)
+		return
+	}
+	if !dbExist() {
+		fmt.Printf("数据库不存在...")
+		os.Exit(1)
+	}
+	txHash, err := hex.DecodeString(txid)
+	if err != nil {
+		fmt.Printf("交易哈希格式错误：%v\n", err)
+		os.Exit(1)
+	}
+	blockchain := BlockchainObject()
+	defer blockchain.DB.Close()
+	tx, block := blockchain.FindTransaction(txHash)
+	if tx == nil {
+		fmt.Printf("交易 [%s] 不存在\n", txid)
+		os.Exit(1)
+	}
+	cli.output(NewTxResult(tx, block, blockchain.Height()), func() {
+		fmt.Printf("\tBlockHash：%x\n", block.Hash)
+		fmt.Printf("\tHeight：%d\n", block.Height)
+		tx.PrintTransaction()
+	})
+}
+
+// 启动节点，直到收到中断信号
+func (cli *CLI) startNode(port int, seed string, mine bool, outbound int, rpcConfig RPCConfig, httpAddr string, notifyConfig NotifyConfig) {
+	blockchain, err := OpenBlockChain(dbName)
+	if err != nil {
+		fmt.Printf("打开数据库失败：%v\n", err)
+		os.Exit(1)
+	}
+	defer blockchain.DB.Close()
+
+	node := NewNode(fmt.Sprintf("localhost:%d", port), blockchain)
+	if notifyConfig.BlockNotify != "" || notifyConfig.WalletNotify != "" {
+		hooks := NewNotifyHooks(blockchain, notifyConfig)
+		defer hooks.Stop()
+	}
+	node.SetMining(mine)
+	node.SetTargetOutbound(outbound)
+	if err := node.Start(); err != nil {
+		fmt.Printf("启动节点失败：%v\n", err)
+		os.Exit(1)
+	}
+	if seed != "" {
+		if err := node.Connect(seed); err != nil {
+			fmt.Printf("连接节点失败：%v\n", err)
+		}
+	}
+	var rpcServer *RPCServer
+	if rpcConfig.Addr != "" {
+		rpcServer = NewRPCServer(node, rpcConfig)
+		if err := rpcServer.Start(); err != nil {
+			fmt.Printf("启动RPC服务失败：%v\n", err)
+			node.Stop()
+			os.Exit(1)
+		}
+	}
+	var httpServer *HTTPServer
+	if httpAddr != "" {
+		httpServer = NewHTTPServer(node, httpAddr)
+		if err := httpServer.Start(); err != nil {
+			fmt.Printf("启动区块浏览器失败：%v\n", err)
+			if rpcServer != nil {
+				rpcServer.Stop()
+			}
+			node.Stop()
+			os.Exit(1)
+		}
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	<-interrupt
+	fmt.Printf("正在停止节点...\n")
+	if httpServer != nil {
+		httpServer.Stop()
+	}
+	if rpcServer != nil {
+		rpcServer.Stop()
+	}
+	node.Stop()
+}
+
+// 打开数据库中的封禁列表，节点运行时数据库被占用，需要先停止节点或通过-rpcconnect访问节点
+func openBanManager() (*BlockChain, *BanManager) {
+	if !dbExist() {
+		fmt.Printf("数据库不存在...")
+		os.Exit(1)
+	}
+	blockchain, err := OpenBlockChain(dbName)
+	if err != nil {
+		fmt.Printf("打开数据库失败：%v\n", err)
+		os.Exit(1)
+	}
+	banManager, err := NewBanManager(blockchain.DB)
+	if err != nil {
+		blockchain.DB.Close()
+		fmt.Printf("读取封禁列表失败：%v\n", err)
+		os.Exit(1)
+	}
+	return blockchain, banManager
+}
+
+// 列出被封禁的节点
+func (cli *CLI) listBanned() {
+	var results []*BanResult
+	if cli.rpc != nil {
+		cli.call("listbanned", &results)
+	} else {
+		blockchain, banManager := openBanManager()
+		defer blockchain.DB.Close()
+		results = NewBanResults(banManager.List())
+	}
+	cli.output(results, func() {
+		if len(results) == 0 {
+			fmt.Printf("封禁列表为空\n")
+			return
+		}
+		for _, result := range results {
+			fmt.Printf("\t地址：%s\n", result.Address)
+			fmt.Printf("\t\t封禁时间：%s\n", time.Unix(result.Created, 0).Format("2006-01-02 15:04:05"))
+			fmt.Printf("\t\t解除时间：%s\n", time.Unix(result.Until, 0).Format("2006-01-02 15:04:05"))
+			fmt.Printf("\t\t原因：%s\n", result.Reason)
+		}
+	})
+}
+
+// 封禁或解除封禁节点
+func (cli *CLI) setBan(addr string, bantime int64, remove bool) {
+	if cli.rpc != nil {
+		cli.clientSetBan(addr, bantime, remove)
+		return
+	}
+	blockchain, banManager := openBanManager()
+	defer blockchain.DB.Close()
+	if remove {
+		removed, err := banManager.Unban(addr)
+		if err != nil {
+			fmt.Printf("解除封禁失败：%v\n", err)
+			os.Exit(1)
+		}
+		if !removed {
+			fmt.Printf("地址 [%s] 未被封禁\n", addr)
+			os.Exit(1)
+		}
+		cli.printBanStatus(&BanStatusResult{Address: addr})
+		return
+	}
+	if err := banManager.Ban(addr, time.Duration(bantime)*time.Second, "manually banned"); err != nil {
+		fmt.Printf("封禁失败：%v\n", err)
+		os.Exit(1)
+	}
+	until := time.Now().Add(time.Duration(bantime) * time.Second)
+	cli.printBanStatus(&BanStatusResult{Address: addr, Banned: true, Until: until.Unix()})
+}
+
+// 输出封禁或解除封禁的结果
+func (cli *CLI) printBanStatus(result *BanStatusResult) {
+	cli.output(result, func() {
+		if result.Banned {
+			fmt.Printf("已封禁 [%s]，直到 [%s]\n", result.Address, time.Unix(result.Until, 0).Format("2006-01-02 15:04:05"))
+		} else {
+			fmt.Printf("已解除封禁 [%s]\n", result.Address)
+		}
+	})
+}
+
+// 清空封禁列表
+func (cli *CLI) clearBanned() {
+	if cli.rpc != nil {
+		cli.call("clearbanned", nil)
+	} else {
+		blockchain, banManager := openBanManager()
+		defer blockchain.DB.Close()
+		if err := banManager.Clear(); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+	}
+	cli.output(map[string]bool{"cleared": true}, func() {
+		fmt.Printf("封禁列表已清空\n")
+	})
 }
 
 // 初始化区块链
 func (cli *CLI) createBlockchain(address string) {
-	CreateBlockChainWithGenesisBlock(address)
+	blockchain := CreateBlockChainWithGenesisBlock(address)
+	defer blockchain.DB.Close()
+	genesis := blockchain.TipBlock()
+	cli.output(NewBlockResult(genesis, genesis.Height, false), func() {})
 }
 
 // 添加区块
@@ -75,32 +554,126 @@ func (cli *CLI) addBlock(txs []*Transaction) {
 	blockchain.AddBlock(txs)
 }
 
-// 打印完整的区块信息
-func (cli *CLI) printChain() {
+// 打印主链上满足条件的区块，headersOnly为true时只输出区块头
+func (cli *CLI) printChain(filter *BlockFilter, headersOnly bool) {
+	if cli.rpc != nil {
+		cli.clientPrintChain(filter, headersOnly)
+		return
+	}
 	if !dbExist() {
 		fmt.Printf("数据库不存在...")
 		os.Exit(1)
 	}
 	// 获取bc对象
 	blockchain := BlockchainObject()
-	blockchain.PrintChain()
+	defer blockchain.DB.Close()
+	cli.printBlocks(blockchain.FilterBlocks(filter), blockchain.Height(), headersOnly)
+}
+
+// 输出区块列表
+func (cli *CLI) printBlocks(blocks []*Block, tipHeight int64, headersOnly bool) {
+	cli.output(NewChainResult(blocks, tipHeight, headersOnly), func() {
+		fmt.Println("打印区块完整信息...")
+		for _, block := range blocks {
+			fmt.Println("---------------------------------")
+			if headersOnly {
+				printBlockHeader(block)
+			} else {
+				printBlock(block)
+			}
+		}
+	})
 }
 
 // 命令行运行函数
 func (cli *CLI) Run() {
 	// 检测参数数量
 	IsValidArgs()
+	// 全局参数
+	globalCmd := flag.NewFlagSet("bc", flag.ExitOnError)
+	flagDataDirArg := globalCmd.String("datadir", ".", "数据目录")
+	flagRPCConnectArg := globalCmd.String("rpcconnect", "", "运行中的节点的RPC地址")
+	flagRPCUserArg := globalCmd.String("rpcuser", "", "RPC认证的用户名")
+	flagRPCPasswordArg := globalCmd.String("rpcpassword", "", "RPC认证的密码")
+	flagLogLevelArg := globalCmd.String("loglevel", defaultLogLevel.String(), "日志级别")
+	flagLogFormatArg := globalCmd.String("logformat", "text", "日志格式：text|json")
+	flagLogFileArg := globalCmd.String("logfile", "", "日志文件")
+	flagLogMaxSizeArg := globalCmd.Int64("logmaxsize", defaultLogMaxSize/1024/1024, "日志文件轮转大小(MB)")
+	flagLogMaxFilesArg := globalCmd.Int("logmaxfiles", defaultLogMaxFiles, "保留的历史日志文件数量")
+	flagJSONArg := globalCmd.Bool("json", false, "以JSON格式输出命令结果")
+	if err := globalCmd.Parse(os.Args[1:]); err != nil {
+		cliLog.Panicf("parse global flags failed! %v\n", err)
+	}
+	if *flagLogFormatArg != "text" && *flagLogFormatArg != "json" {
+		fmt.Printf("无效的日志格式 [%s]，可选text|json\n", *flagLogFormatArg)
+		os.Exit(1)
+	}
+	if *flagLogMaxSizeArg <= 0 || *flagLogMaxFilesArg < 0 {
+		fmt.Printf("-logmaxsize需要大于0，-logmaxfiles不能小于0\n")
+		os.Exit(1)
+	}
+	if err := InitLogging(LogConfig{
+		Level:    *flagLogLevelArg,
+		JSON:     *flagLogFormatArg == "json",
+		File:     *flagLogFileArg,
+		MaxSize:  *flagLogMaxSizeArg * 1024 * 1024,
+		MaxFiles: *flagLogMaxFilesArg,
+	}); err != nil {
+		fmt.Printf("初始化日志失败：%v\n", err)
+		os.Exit(1)
+	}
+	args := globalCmd.Args()
+	if len(args) == 0 {
+		PrintUsage()
+		os.Exit(1)
+	}
+	SetDataDir(*flagDataDirArg)
+	cli.json = *flagJSONArg
+	if *flagRPCConnectArg != "" {
+		if (*flagRPCUserArg == "") != (*flagRPCPasswordArg == "") {
+			fmt.Printf("-rpcuser与-rpcpassword需要同时指定\n")
+			os.Exit(1)
+		}
+		client, err := NewRPCClient(*flagRPCConnectArg, *flagRPCUserArg, *flagRPCPasswordArg)
+		if err != nil {
+			fmt.Printf("连接节点失败：%v\n", err)
+			os.Exit(1)
+		}
+		cli.rpc = client
+	}
 	// 新建相关命令
 	// 添加区块
 	addBlockCmd := flag.NewFlagSet("addblock", flag.ExitOnError)
 	// 输出区块链完整信息
 	printChainCmd := flag.NewFlagSet("printchain", flag.ExitOnError)
+	flagPrintChainFromArg := printChainCmd.Int64("from", 0, "最低高度")
+	flagPrintChainToArg := printChainCmd.Int64("to", 0, "最高高度")
+	flagPrintChainLimitArg := printChainCmd.Int("limit", 0, "最多输出的区块数量")
+	flagPrintChainReverseArg := printChainCmd.Bool("reverse", false, "从创世区块开始输出")
+	flagPrintChainHeadersOnlyArg := printChainCmd.Bool("headers-only", false, "只输出区块头")
+	flagPrintChainAddressArg := printChainCmd.String("address", "", "只输出涉及该地址的区块")
+	flagPrintChainSinceArg := printChainCmd.Int64("since", 0, "只输出该时间戳之后的区块")
 	// 创建区块链
 	createBLCWithGenesisBlockCmd := flag.NewFlagSet("createblockchain", flag.ExitOnError)
 	// 发起交易
 	sendCmd := flag.NewFlagSet("send", flag.ExitOnError)
 	// 查询余额
 	getBalanceCmd := flag.NewFlagSet("getbalance", flag.ExitOnError)
+	listUnspentCmd := flag.NewFlagSet("listunspent", flag.ExitOnError)
+	getHistoryCmd := flag.NewFlagSet("gethistory", flag.ExitOnError)
+	// 原始交易
+	createRawTxCmd := flag.NewFlagSet("createrawtransaction", flag.ExitOnError)
+	decodeRawTxCmd := flag.NewFlagSet("decoderawtransaction", flag.ExitOnError)
+	signRawTxCmd := flag.NewFlagSet("signrawtransaction", flag.ExitOnError)
+	sendRawTxCmd := flag.NewFlagSet("sendrawtransaction", flag.ExitOnError)
+	// 查询交易
+	getTransactionCmd := flag.NewFlagSet("gettransaction", flag.ExitOnError)
+	// 启动节点
+	startNodeCmd := flag.NewFlagSet("startnode", flag.ExitOnError)
+	// 节点封禁
+	listBannedCmd := flag.NewFlagSet("listbanned", flag.ExitOnError)
+	setBanCmd := flag.NewFlagSet("setban", flag.ExitOnError)
+	clearBannedCmd := flag.NewFlagSet("clearbanned", flag.ExitOnError)
 
 	// 数据参数处理
 	// 添加区块
@@ -111,30 +684,113 @@ func (cli *CLI) Run() {
 	flagSendFromArg := sendCmd.String("from", "", "转账源地址")
 	flagSendToArg := sendCmd.String("to", "", "转账目标地址")
 	flagSendAmountArg := sendCmd.String("amount", "", "转账金额")
+	flagSendCoinSelectArg := sendCmd.String("coinselect", defaultCoinSelector, "选币策略：largest|smallest|oldest|bnb")
+	flagSendDataArg := sendCmd.String("data", "", "附加数据")
 	// 查询余额
 	flagGetBalanceArg := getBalanceCmd.String("address", "", "余额")
+	flagGetBalanceHeightArg := getBalanceCmd.Int64("height", 0, "查询该高度的余额")
+	flagGetBalanceTimeArg := getBalanceCmd.Int64("time", 0, "查询该时间戳的余额")
+	// 未花费输出
+	flagListUnspentAddressArg := listUnspentCmd.String("address", "", "查询的地址")
+	flagListUnspentMinConfArg := listUnspentCmd.Int64("minconf", defaultMinConf, "最小确认数")
+	flagListUnspentMaxConfArg := listUnspentCmd.Int64("maxconf", defaultMaxConf, "最大确认数")
+	flagListUnspentSortArg := listUnspentCmd.String("sort", SortUTXOByAge, "排序方式：age|value")
+	flagListUnspentReverseArg := listUnspentCmd.Bool("reverse", false, "反向排序")
+	// 交易历史
+	flagGetHistoryAddressArg := getHistoryCmd.String("address", "", "查询的地址")
+	// 原始交易
+	flagCreateRawTxInputsArg := createRawTxCmd.String("inputs", "", "交易输入，JSON数组")
+	flagCreateRawTxOutputsArg := createRawTxCmd.String("outputs", "", "交易输出，JSON对象")
+	flagCreateRawTxDataArg := createRawTxCmd.String("data", "", "附加数据")
+	flagDecodeRawTxArg := decodeRawTxCmd.String("hex", "", "原始交易")
+	flagSignRawTxArg := signRawTxCmd.String("hex", "", "原始交易")
+	flagSignRawTxAddressArg := signRawTxCmd.String("address", "", "签名地址")
+	flagSendRawTxArg := sendRawTxCmd.String("hex", "", "已签名的原始交易")
+	flagSendRawTxNodeArg := sendRawTxCmd.String("node", "", "接收交易的节点地址")
+	// 查询交易
+	flagGetTransactionArg := getTransactionCmd.String("txid", "", "交易哈希")
+	// 启动节点
+	flagStartNodePortArg := startNodeCmd.Int("port", 0, "监听端口")
+	flagStartNodeSeedArg := startNodeCmd.String("seed", "", "启动时连接的节点")
+	flagStartNodeMineArg := startNodeCmd.Bool("mine", false, "将交易池中的交易打包成区块")
+	flagStartNodeOutboundArg := startNodeCmd.Int("outbound", defaultTargetOutbound, "维持的主动连接数量")
+	flagStartNodeRPCArg := startNodeCmd.String("rpc", "", "JSON-RPC服务监听地址")
+	flagStartNodeRPCUserArg := startNodeCmd.String("rpcuser", "", "RPC认证的用户名")
+	flagStartNodeRPCPasswordArg := startNodeCmd.String("rpcpassword", "", "RPC认证的密码")
+	flagStartNodeHTTPArg := startNodeCmd.String("http", "", "REST接口与区块浏览器监听地址")
+	flagStartNodeBlockNotifyArg := startNodeCmd.String("blocknotify", "", "区块接入主链时执行的命令")
+	flagStartNodeWalletNotifyArg := startNodeCmd.String("walletnotify", "", "涉及钱包地址的交易出现时执行的命令")
+	flagStartNodeWalletAddressArg := startNodeCmd.String("walletaddress", "", "钱包地址，多个地址以逗号分隔")
+	// 节点封禁
+	flagSetBanAddrArg := setBanCmd.String("addr", "", "封禁的地址(主机或主机:端口)")
+	flagSetBanTimeArg := setBanCmd.Int64("bantime", int64(defaultBanDuration/time.Second), "封禁时间(秒)")
+	flagSetBanRemoveArg := setBanCmd.Bool("remove", false, "解除封禁")
 
 	// 判断命令
-	switch os.Args[1] {
+	switch args[0] {
 	case "getbalance":
-		if err := getBalanceCmd.Parse(os.Args[2:]); err != nil {
-			log.Panicf("parse getbalanceCmd failed! %v\n", err)
+		if err := getBalanceCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse getbalanceCmd failed! %v\n", err)
+		}
+	case "listunspent":
+		if err := listUnspentCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse listUnspentCmd failed! %v\n", err)
+		}
+	case "gethistory":
+		if err := getHistoryCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse getHistoryCmd failed! %v\n", err)
 		}
 	case "send":
-		if err := sendCmd.Parse(os.Args[2:]); err != nil {
-			log.Panicf("parse sendCmd failed! %v\n", err)
+		if err := sendCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse sendCmd failed! %v\n", err)
 		}
 	case "addblock":
-		if err := addBlockCmd.Parse(os.Args[2:]); err != nil {
-			log.Panicf("parse addBlockCmd failed! %v\n", err)
+		if err := addBlockCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse addBlockCmd failed! %v\n", err)
 		}
 	case "printchain":
-		if err := printChainCmd.Parse(os.Args[2:]); err != nil {
-			log.Panicf("parse printChainCmd failed! %v\n", err)
+		if err := printChainCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse printChainCmd failed! %v\n", err)
 		}
 	case "createblockchain":
-		if err := createBLCWithGenesisBlockCmd.Parse(os.Args[2:]); err != nil {
-			log.Panicf("parse createBLCWithGenesisBlockCmd failed! %v\n", err)
+		if err := createBLCWithGenesisBlockCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse createBLCWithGenesisBlockCmd failed! %v\n", err)
+		}
+	case "createrawtransaction":
+		if err := createRawTxCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse createRawTxCmd failed! %v\n", err)
+		}
+	case "decoderawtransaction":
+		if err := decodeRawTxCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse decodeRawTxCmd failed! %v\n", err)
+		}
+	case "signrawtransaction":
+		if err := signRawTxCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse signRawTxCmd failed! %v\n", err)
+		}
+	case "sendrawtransaction":
+		if err := sendRawTxCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse sendRawTxCmd failed! %v\n", err)
+		}
+	case "gettransaction":
+		if err := getTransactionCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse getTransactionCmd failed! %v\n", err)
+		}
+	case "startnode":
+		if err := startNodeCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse startNodeCmd failed! %v\n", err)
+		}
+	case "listbanned":
+		if err := listBannedCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse listBannedCmd failed! %v\n", err)
+		}
+	case "setban":
+		if err := setBanCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse setBanCmd failed! %v\n", err)
+		}
+	case "clearbanned":
+		if err := clearBannedCmd.Parse(args[1:]); err != nil {
+			cliLog.Panicf("parse clearBannedCmd failed! %v\n", err)
 		}
 	default:
 		PrintUsage()
@@ -151,7 +807,22 @@ func (cli *CLI) Run() {
 	}
 	// 输出区块链信息
 	if printChainCmd.Parsed() {
-		cli.printChain()
+		if *flagPrintChainFromArg < 0 || *flagPrintChainToArg < 0 || *flagPrintChainLimitArg < 0 || *flagPrintChainSinceArg < 0 {
+			fmt.Printf("-from、-to、-limit、-since不能为负数\n")
+			os.Exit(1)
+		}
+		if *flagPrintChainToArg > 0 && *flagPrintChainFromArg > *flagPrintChainToArg {
+			fmt.Printf("-from不能大于-to\n")
+			os.Exit(1)
+		}
+		cli.printChain(&BlockFilter{
+			From:    *flagPrintChainFromArg,
+			To:      *flagPrintChainToArg,
+			Since:   *flagPrintChainSinceArg,
+			Address: *flagPrintChainAddressArg,
+			Limit:   *flagPrintChainLimitArg,
+			Reverse: *flagPrintChainReverseArg,
+		}, *flagPrintChainHeadersOnlyArg)
 	}
 	// 创建区块链命令
 	if createBLCWithGenesisBlockCmd.Parsed() {
@@ -173,15 +844,42 @@ func (cli *CLI) Run() {
 			PrintUsage()
 			os.Exit(1)
 		}
+		if _, err := NewCoinSelector(*flagSendCoinSelectArg); err != nil {
+			fmt.Printf("选币策略 [%s] 不存在...\n", *flagSendCoinSelectArg)
+			PrintUsage()
+			os.Exit(1)
+		}
+		data, err := ParseDataArg(*flagSendDataArg)
+		if err != nil {
+			fmt.Printf("附加数据格式错误：%v\n", err)
+			os.Exit(1)
+		}
+		// 一对多转账：-to为JSON对象，金额包含在其中
+		if IsJSONObject(*flagSendToArg) {
+			fromArgs := JSONToSlice(*flagSendFromArg)
+			if len(fromArgs) != 1 {
+				fmt.Printf("一对多转账只能指定一个源地址...\n")
+				os.Exit(1)
+			}
+			outputs := JSONToOutputs(*flagSendToArg)
+			if !cli.json {
+				fmt.Printf("\tFROM:[%s]\n", fromArgs[0])
+				fmt.Printf("\tTO:%v\n", outputs)
+			}
+			cli.sendMany(fromArgs[0], outputs, *flagSendCoinSelectArg, data)
+			return
+		}
 		if *flagSendAmountArg == "" {
 			fmt.Printf("转账金额不能为空...\n")
 			PrintUsage()
 			os.Exit(1)
 		}
-		fmt.Printf("\tFROM:[%s]\n", JSONToSlice(*flagSendFromArg))
-		fmt.Printf("\tTO:[%s]\n", JSONToSlice(*flagSendToArg))
-		fmt.Printf("\tAMOUNT:[%s]\n", JSONToSlice(*flagSendAmountArg))
-		cli.send(JSONToSlice(*flagSendFromArg), JSONToSlice(*flagSendToArg), JSONToSlice(*flagSendAmountArg))
+		if !cli.json {
+			fmt.Printf("\tFROM:[%s]\n", JSONToSlice(*flagSendFromArg))
+			fmt.Printf("\tTO:[%s]\n", JSONToSlice(*flagSendToArg))
+			fmt.Printf("\tAMOUNT:[%s]\n", JSONToSlice(*flagSendAmountArg))
+		}
+		cli.send(JSONToSlice(*flagSendFromArg), JSONToSlice(*flagSendToArg), JSONToSlice(*flagSendAmountArg), *flagSendCoinSelectArg, data)
 	}
 	// 查询余额
 	if getBalanceCmd.Parsed() {
@@ -190,6 +888,140 @@ func (cli *CLI) Run() {
 			PrintUsage()
 			os.Exit(1)
 		}
-		cli.getBalance(*flagGetBalanceArg)
+		if *flagGetBalanceHeightArg < 0 || *flagGetBalanceTimeArg < 0 {
+			fmt.Printf("-height、-time不能为负数\n")
+			os.Exit(1)
+		}
+		if *flagGetBalanceHeightArg > 0 && *flagGetBalanceTimeArg > 0 {
+			fmt.Printf("-height与-time不能同时指定\n")
+			os.Exit(1)
+		}
+		cli.getBalance(*flagGetBalanceArg, *flagGetBalanceHeightArg, *flagGetBalanceTimeArg)
+	}
+	// 列出未花费输出
+	if listUnspentCmd.Parsed() {
+		if *flagListUnspentAddressArg == "" {
+			fmt.Printf("查询地址不能为空\n")
+			PrintUsage()
+			os.Exit(1)
+		}
+		if *flagListUnspentMinConfArg < 0 || *flagListUnspentMaxConfArg < *flagListUnspentMinConfArg {
+			fmt.Printf("确认数范围无效：-minconf不能为负数，-maxconf不能小于-minconf\n")
+			os.Exit(1)
+		}
+		cli.listUnspent(*flagListUnspentAddressArg, *flagListUnspentMinConfArg, *flagListUnspentMaxConfArg, *flagListUnspentSortArg, *flagListUnspentReverseArg)
+	}
+	// 交易历史
+	if getHistoryCmd.Parsed() {
+		if *flagGetHistoryAddressArg == "" {
+			fmt.Printf("查询地址不能为空\n")
+			PrintUsage()
+			os.Exit(1)
+		}
+		cli.getHistory(*flagGetHistoryAddressArg)
+	}
+	// 创建原始交易
+	if createRawTxCmd.Parsed() {
+		if *flagCreateRawTxInputsArg == "" || *flagCreateRawTxOutputsArg == "" {
+			fmt.Printf("交易输入和输出不能为空\n")
+			PrintUsage()
+			os.Exit(1)
+		}
+		data, err := ParseDataArg(*flagCreateRawTxDataArg)
+		if err != nil {
+			fmt.Printf("附加数据格式错误：%v\n", err)
+			os.Exit(1)
+		}
+		cli.createRawTransaction(JSONToRawInputs(*flagCreateRawTxInputsArg), JSONToOutputs(*flagCreateRawTxOutputsArg), data)
+	}
+	// 解析原始交易
+	if decodeRawTxCmd.Parsed() {
+		if *flagDecodeRawTxArg == "" {
+			fmt.Printf("原始交易不能为空\n")
+			PrintUsage()
+			os.Exit(1)
+		}
+		cli.decodeRawTransaction(*flagDecodeRawTxArg)
+	}
+	// 原始交易签名
+	if signRawTxCmd.Parsed() {
+		if *flagSignRawTxArg == "" || *flagSignRawTxAddressArg == "" {
+			fmt.Printf("原始交易和签名地址不能为空\n")
+			PrintUsage()
+			os.Exit(1)
+		}
+		cli.signRawTransaction(*flagSignRawTxArg, *flagSignRawTxAddressArg)
+	}
+	// 提交原始交易
+	if sendRawTxCmd.Parsed() {
+		if *flagSendRawTxArg == "" {
+			fmt.Printf("原始交易不能为空\n")
+			PrintUsage()
+			os.Exit(1)
+		}
+		cli.sendRawTransaction(*flagSendRawTxArg, *flagSendRawTxNodeArg)
+	}
+	// 查询交易
+	if getTransactionCmd.Parsed() {
+		if *flagGetTransactionArg == "" {
+			fmt.Printf("交易哈希不能为空\n")
+			PrintUsage()
+			os.Exit(1)
+		}
+		cli.getTransaction(*flagGetTransactionArg)
+	}
+	// 启动节点
+	if startNodeCmd.Parsed() {
+		if *flagStartNodePortArg <= 0 {
+			fmt.Printf("监听端口不能为空\n")
+			PrintUsage()
+			os.Exit(1)
+		}
+		if *flagStartNodeOutboundArg < 0 {
+			fmt.Printf("主动连接数量不能为负数\n")
+			os.Exit(1)
+		}
+		if (*flagStartNodeRPCUserArg == "") != (*flagStartNodeRPCPasswordArg == "") {
+			fmt.Printf("-rpcuser与-rpcpassword需要同时指定\n")
+			os.Exit(1)
+		}
+		rpcConfig := RPCConfig{
+			Addr:     *flagStartNodeRPCArg,
+			User:     *flagStartNodeRPCUserArg,
+			Password: *flagStartNodeRPCPasswordArg,
+		}
+		notifyConfig := NotifyConfig{
+			BlockNotify:  *flagStartNodeBlockNotifyArg,
+			WalletNotify: *flagStartNodeWalletNotifyArg,
+		}
+		for _, address := range strings.Split(*flagStartNodeWalletAddressArg, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				notifyConfig.WalletAddresses = append(notifyConfig.WalletAddresses, address)
+			}
+		}
+		if notifyConfig.WalletNotify != "" && len(notifyConfig.WalletAddresses) == 0 {
+			fmt.Printf("-walletnotify需要通过-walletaddress指定钱包地址\n")
+			os.Exit(1)
+		}
+		cli.startNode(*flagStartNodePortArg, *flagStartNodeSeedArg, *flagStartNodeMineArg, *flagStartNodeOutboundArg, rpcConfig, *flagStartNodeHTTPArg, notifyConfig)
+	}
+	// 节点封禁
+	if listBannedCmd.Parsed() {
+		cli.listBanned()
+	}
+	if setBanCmd.Parsed() {
+		if *flagSetBanAddrArg == "" {
+			fmt.Printf("封禁地址不能为空\n")
+			PrintUsage()
+			os.Exit(1)
+		}
+		if !*flagSetBanRemoveArg && *flagSetBanTimeArg <= 0 {
+			fmt.Printf("封禁时间必须大于0\n")
+			os.Exit(1)
+		}
+		cli.setBan(*flagSetBanAddrArg, *flagSetBanTimeArg, *flagSetBanRemoveArg)
+	}
+	if clearBannedCmd.Parsed() {
+		cli.clearBanned()
 	}
 }
@@ -0,0 +1,145 @@
+package BLC
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// 命令行客户端模式管理文件
+// 指定-rpcconnect时，getbalance、listunspent、gethistory、send、sendrawtransaction、printchain、gettransaction及封禁命令通过RPC访问运行中的节点，
+// 不打开本地数据库，输出格式(包括-json)与直接访问数据库时相同
+
+// 调用RPC方法，失败时退出
+func (cli *CLI) call(method string, result interface{}, params ...interface{}) {
+	if err := cli.rpc.Call(method, result, params...); err != nil {
+		fmt.Printf("调用节点 [%s] 失败：%v\n", method, err)
+		os.Exit(1)
+	}
+}
+
+// 查询余额
+func (cli *CLI) clientGetBalance(from string, height, t int64) {
+	var amount Amount
+	cli.call("getbalance", &amount, from, height, t)
+	cli.printBalance(&BalanceResult{from, amount, height, t})
+}
+
+// 发起交易，每组参数生成一笔交易并提交到节点的交易池，附加数据添加到第一笔交易
+func (cli *CLI) clientSend(from, to, amount []string, coinSelect string, data []byte) {
+	if len(from) != len(to) || len(from) != len(amount) {
+		fmt.Printf("交易参数输入有误，请检查一致性...\n")
+		os.Exit(1)
+	}
+	values := make([]Amount, len(amount))
+	for index, value := range amount {
+		parsed, err := ParsePositiveAmount(value)
+		if err != nil {
+			fmt.Printf("转账金额 [%s] 无效：%v\n", value, err)
+			os.Exit(1)
+		}
+		values[index] = parsed
+	}
+	result := &SendResult{TxIDs: []string{}}
+	for index := range from {
+		var txid string
+		var dataHex string
+		if index == 0 {
+			dataHex = hex.EncodeToString(data)
+		}
+		cli.call("sendmany", &txid, from[index], map[string]Amount{to[index]: values[index]}, dataHex, coinSelect)
+		result.TxIDs = append(result.TxIDs, txid)
+	}
+	cli.printSubmitResult(result)
+}
+
+// 发起一对多转账，生成一笔交易并提交到节点的交易池
+func (cli *CLI) clientSendMany(from string, outputs map[string]Amount, coinSelect string, data []byte) {
+	var txid string
+	cli.call("sendmany", &txid, from, outputs, hex.EncodeToString(data), coinSelect)
+	cli.printSubmitResult(&SendResult{TxIDs: []string{txid}})
+}
+
+// 提交原始交易到节点的交易池
+func (cli *CLI) clientSendRawTransaction(rawHex string) {
+	var txid string
+	cli.call("sendrawtransaction", &txid, rawHex)
+	cli.printSubmitResult(&SendResult{TxIDs: []string{txid}})
+}
+
+// 输出提交到交易池的交易
+func (cli *CLI) printSubmitResult(result *SendResult) {
+	cli.output(result, func() {
+		for _, txid := range result.TxIDs {
+			fmt.Printf("\ttxid：%s\n", txid)
+		}
+	})
+}
+
+// 打印主链上满足条件的区块，在高度范围内逐个查询区块后筛选
+func (cli *CLI) clientPrintChain(filter *BlockFilter, headersOnly bool) {
+	var tipHeight int64
+	cli.call("getblockcount", &tipHeight)
+	low, high := int64(1), tipHeight
+	if filter.From > low {
+		low = filter.From
+	}
+	if filter.To > 0 && filter.To < high {
+		high = filter.To
+	}
+	var blocks []*Block
+	for i := int64(0); i <= high-low; i++ {
+		height := high - i
+		if filter.Reverse {
+			height = low + i
+		}
+		var hash string
+		cli.call("getblockhash", &hash, height)
+		var result BlockResult
+		cli.call("getblock", &result, hash, true)
+		block, err := result.Block()
+		if err != nil {
+			fmt.Printf("解析区块失败：%v\n", err)
+			os.Exit(1)
+		}
+		if !filter.Match(block) {
+			continue
+		}
+		blocks = append(blocks, block)
+		if filter.Limit > 0 && len(blocks) == filter.Limit {
+			break
+		}
+	}
+	cli.printBlocks(blocks, tipHeight, headersOnly)
+}
+
+// 查询指定交易，包括节点交易池中的交易
+func (cli *CLI) clientGetTransaction(txid string) {
+	var result TxResult
+	cli.call("gettransaction", &result, txid)
+	tx, err := result.Transaction()
+	if err != nil {
+		fmt.Printf("解析交易失败：%v\n", err)
+		os.Exit(1)
+	}
+	cli.output(&result, func() {
+		if result.BlockHash == "" {
+			fmt.Printf("\t交易尚在交易池中\n")
+		} else {
+			fmt.Printf("\tBlockHash：%s\n", result.BlockHash)
+			fmt.Printf("\tHeight：%d\n", result.Height)
+		}
+		tx.PrintTransaction()
+	})
+}
+
+// 封禁或解除封禁节点，封禁立即生效并断开与该地址的连接
+func (cli *CLI) clientSetBan(addr string, bantime int64, remove bool) {
+	var result BanStatusResult
+	if remove {
+		cli.call("setban", &result, addr, "remove")
+	} else {
+		cli.call("setban", &result, addr, "add", bantime)
+	}
+	cli.printBanStatus(&result)
+}
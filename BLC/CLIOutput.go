@@ -0,0 +1,107 @@
+package BLC
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+)
+
+// 命令行输出管理文件
+// 指定全局参数-json时，命令的结果以JSON文档输出到标准输出，哈希为hex字符串，金额为十进制数字
+// 区块与交易的JSON表示与RPC接口相同(见RPCTypes.go)；出错时仍输出文本错误信息并以状态码1退出
+
+// 地址余额，指定-height或-time时同时给出查询的高度或时间
+type BalanceResult struct {
+	Address string `json:"address"`
+	Balance Amount `json:"balance"`
+	Height  int64  `json:"height,omitempty"`
+	Time    int64  `json:"time,omitempty"`
+}
+
+// 转账结果，交易提交到节点的交易池时区块哈希与高度为空
+type SendResult struct {
+	TxIDs     []string `json:"txids"`
+	BlockHash string   `json:"blockhash,omitempty"`
+	Height    int64    `json:"height,omitempty"`
+}
+
+// printchain输出的区块列表
+type ChainResult struct {
+	Height int64          `json:"height"` // 最新区块高度
+	Blocks []*BlockResult `json:"blocks"`
+}
+
+// 原始交易
+type RawTxResult struct {
+	Hex    string `json:"hex"`
+	Signed *int   `json:"signed,omitempty"` // 本次签名的输入数量，仅signrawtransaction提供
+}
+
+// 解析的原始交易
+type DecodedTxResult struct {
+	*TxResult
+	Complete bool `json:"complete"` // 所有输入均已签名
+}
+
+// 封禁的节点
+type BanResult struct {
+	Address string `json:"address"`
+	Created int64  `json:"created"`
+	Until   int64  `json:"until"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// 封禁或解除封禁的结果
+type BanStatusResult struct {
+	Address string `json:"address"`
+	Banned  bool   `json:"banned"`
+	Until   int64  `json:"until,omitempty"`
+}
+
+// 封禁列表的JSON表示
+func NewBanResults(entries []*BanEntry) []*BanResult {
+	results := []*BanResult{}
+	for _, entry := range entries {
+		results = append(results, &BanResult{entry.Addr, entry.Created, entry.Until, entry.Reason})
+	}
+	return results
+}
+
+// 区块列表，headersOnly为true时不包含交易
+func NewChainResult(blocks []*Block, tipHeight int64, headersOnly bool) *ChainResult {
+	result := &ChainResult{Height: tipHeight, Blocks: []*BlockResult{}}
+	for _, block := range blocks {
+		blockResult := NewBlockResult(block, tipHeight, !headersOnly)
+		if headersOnly {
+			blockResult.TxIDs = nil
+		}
+		result.Blocks = append(result.Blocks, blockResult)
+	}
+	return result
+}
+
+// 区块中交易的转账结果
+func NewSendResult(block *Block) *SendResult {
+	result := &SendResult{
+		TxIDs:     []string{},
+		BlockHash: hex.EncodeToString(block.Hash),
+		Height:    block.Height,
+	}
+	for _, tx := range block.Txs {
+		result.TxIDs = append(result.TxIDs, hex.EncodeToString(tx.TxHash))
+	}
+	return result
+}
+
+// 输出命令结果：-json时输出value的JSON表示，否则调用text输出文本
+func (cli *CLI) output(value interface{}, text func()) {
+	if !cli.json {
+		text()
+		return
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		cliLog.Fatalf("encode json output failed! %v\n", err)
+	}
+}
@@ -0,0 +1,137 @@
+package BLC
+
+import (
+	"fmt"
+	"sort"
+)
+
+// 选币策略管理文件
+
+// 选币策略接口
+// 从候选UTXO中选出总额不小于amount的集合
+type CoinSelector interface {
+	Select(utxos []*UTXO, amount Amount) ([]*UTXO, error)
+}
+
+// 默认选币策略
+const defaultCoinSelector = "largest"
+
+// 根据名称获取选币策略
+func NewCoinSelector(name string) (CoinSelector, error) {
+	switch name {
+	case "", "largest":
+		return LargestFirstSelector{}, nil
+	case "smallest":
+		return SmallestFirstSelector{}, nil
+	case "oldest":
+		return OldestFirstSelector{}, nil
+	case "bnb":
+		return BranchAndBoundSelector{Fallback: LargestFirstSelector{}}, nil
+	}
+	return nil, fmt.Errorf("unknown coin selector [%s]", name)
+}
+
+// 大额优先：优先使用金额大的UTXO，输入数量最少
+type LargestFirstSelector struct{}
+
+func (LargestFirstSelector) Select(utxos []*UTXO, amount Amount) ([]*UTXO, error) {
+	sorted := copyUTXOs(utxos)
+	SortUTXOs(sorted, SortUTXOByValue, false)
+	return accumulateUTXOs(sorted, amount)
+}
+
+// 小额优先：优先使用金额小的UTXO，用于归集零钱
+type SmallestFirstSelector struct{}
+
+func (SmallestFirstSelector) Select(utxos []*UTXO, amount Amount) ([]*UTXO, error) {
+	sorted := copyUTXOs(utxos)
+	SortUTXOs(sorted, SortUTXOByValue, true)
+	return accumulateUTXOs(sorted, amount)
+}
+
+// 最早优先：按UTXO所在区块高度从低到高使用，未确认的UTXO放在最后
+type OldestFirstSelector struct{}
+
+func (OldestFirstSelector) Select(utxos []*UTXO, amount Amount) ([]*UTXO, error) {
+	sorted := copyUTXOs(utxos)
+	SortUTXOs(sorted, SortUTXOByAge, false)
+	return accumulateUTXOs(sorted, amount)
+}
+
+// 分支定界搜索的最大尝试次数
+const bnbMaxTries = 100000
+
+// 分支定界：搜索总额恰好等于amount的组合，从而不产生找零输出
+// 找不到精确组合时交给Fallback处理
+type BranchAndBoundSelector struct {
+	Fallback CoinSelector
+}
+
+func (selector BranchAndBoundSelector) Select(utxos []*UTXO, amount Amount) ([]*UTXO, error) {
+	sorted := copyUTXOs(utxos)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Output.Value > sorted[j].Output.Value
+	})
+	// remaining[i]：从第i个开始所有UTXO的总额，用于剪枝
+	remaining := make([]Amount, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + sorted[i].Output.Value
+	}
+
+	var selected []int
+	var found []int
+	tries := 0
+	var search func(index int, value Amount) bool
+	search = func(index int, value Amount) bool {
+		tries++
+		if value == amount {
+			found = append([]int{}, selected...)
+			return true
+		}
+		if value > amount || index == len(sorted) || value+remaining[index] < amount || tries > bnbMaxTries {
+			return false
+		}
+		// 包含当前UTXO
+		selected = append(selected, index)
+		if search(index+1, value+sorted[index].Output.Value) {
+			return true
+		}
+		selected = selected[:len(selected)-1]
+		// 不包含当前UTXO
+		return search(index+1, value)
+	}
+
+	if search(0, 0) {
+		var result []*UTXO
+		for _, index := range found {
+			result = append(result, sorted[index])
+		}
+		return result, nil
+	}
+	if selector.Fallback == nil {
+		return nil, fmt.Errorf("no exact match found for amount [%s]", amount)
+	}
+	return selector.Fallback.Select(utxos, amount)
+}
+
+// 复制UTXO列表，避免排序影响调用方
+func copyUTXOs(utxos []*UTXO) []*UTXO {
+	return append([]*UTXO{}, utxos...)
+}
+
+// 按顺序累加UTXO，直到总额不小于amount
+func accumulateUTXOs(utxos []*UTXO, amount Amount) ([]*UTXO, error) {
+	var value Amount
+	var result []*UTXO
+	for _, utxo := range utxos {
+		var err error
+		if value, err = value.Add(utxo.Output.Value); err != nil {
+			return nil, err
+		}
+		result = append(result, utxo)
+		if value >= amount {
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("insufficient funds: have [%s], need [%s]", value, amount)
+}
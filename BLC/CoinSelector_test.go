@@ -0,0 +1,150 @@
+package BLC
+
+import (
+	"sort"
+	"testing"
+)
+
+func testUTXOs(values ...Amount) []*UTXO {
+	var utxos []*UTXO
+	for i, value := range values {
+		utxos = append(utxos, &UTXO{
+			TxHash: []byte{byte(i)},
+			Index:  i,
+			Output: &TxOutput{Value: value, ScriptPubkey: "alice"},
+			Height: int64(i + 1),
+		})
+	}
+	return utxos
+}
+
+func utxoValues(utxos []*UTXO) []Amount {
+	var values []Amount
+	for _, utxo := range utxos {
+		values = append(values, utxo.Output.Value)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values
+}
+
+func sumUTXOs(utxos []*UTXO) Amount {
+	var sum Amount
+	for _, utxo := range utxos {
+		sum += utxo.Output.Value
+	}
+	return sum
+}
+
+func equalAmounts(a, b []Amount) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBranchAndBoundExactMatch(t *testing.T) {
+	selector := BranchAndBoundSelector{Fallback: LargestFirstSelector{}}
+	tests := []struct {
+		values []Amount
+		amount Amount
+		want   []Amount
+	}{
+		{[]Amount{5, 3, 2}, 5, []Amount{5}},
+		{[]Amount{8, 6, 4, 1}, 7, []Amount{1, 6}},
+		{[]Amount{9, 7, 4, 3}, 14, []Amount{3, 4, 7}},
+		{[]Amount{1, 2, 4, 8, 16}, 31, []Amount{1, 2, 4, 8, 16}},
+	}
+	for _, test := range tests {
+		utxos := testUTXOs(test.values...)
+		got, err := selector.Select(utxos, test.amount)
+		if err != nil {
+			t.Errorf("Select(%v, %d) error: %v", test.values, test.amount, err)
+			continue
+		}
+		if values := utxoValues(got); !equalAmounts(values, test.want) {
+			t.Errorf("Select(%v, %d) = %v, want %v", test.values, test.amount, values, test.want)
+		}
+		// 选币不应改变调用方的列表顺序
+		for i, utxo := range utxos {
+			if utxo.Output.Value != test.values[i] {
+				t.Fatalf("Select reordered the input utxos")
+			}
+		}
+	}
+}
+
+func TestBranchAndBoundFallback(t *testing.T) {
+	utxos := testUTXOs(3, 9, 5)
+	selector := BranchAndBoundSelector{Fallback: LargestFirstSelector{}}
+	got, err := selector.Select(utxos, 11)
+	if err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	// 没有总额为11的组合，按大额优先选出9和5
+	if values := utxoValues(got); !equalAmounts(values, []Amount{5, 9}) {
+		t.Fatalf("fallback selected %v, want [5 9]", values)
+	}
+
+	if _, err := selector.Select(utxos, 18); err == nil {
+		t.Fatal("fallback with insufficient funds succeeded")
+	}
+
+	if _, err := (BranchAndBoundSelector{}).Select(utxos, 11); err == nil {
+		t.Fatal("Select without fallback succeeded without an exact match")
+	}
+}
+
+func TestBranchAndBoundTriesLimit(t *testing.T) {
+	// 全部为偶数，奇数金额不存在精确组合，搜索次数超过上限后交给Fallback
+	var values []Amount
+	for i := 0; i < 40; i++ {
+		values = append(values, Amount(2*(i+1)))
+	}
+	selector := BranchAndBoundSelector{Fallback: SmallestFirstSelector{}}
+	got, err := selector.Select(testUTXOs(values...), 101)
+	if err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if sum := sumUTXOs(got); sum < 101 {
+		t.Fatalf("fallback selected total %d, want at least 101", sum)
+	}
+	if got[0].Output.Value != 2 {
+		t.Fatalf("fallback did not use the smallest first selector: %v", utxoValues(got))
+	}
+}
+
+func TestNewCoinSelector(t *testing.T) {
+	for _, name := range []string{"", "largest", "smallest", "oldest", "bnb"} {
+		if _, err := NewCoinSelector(name); err != nil {
+			t.Errorf("NewCoinSelector(%q) error: %v", name, err)
+		}
+	}
+	if _, err := NewCoinSelector("random"); err == nil {
+		t.Error("NewCoinSelector(\"random\") succeeded")
+	}
+
+	utxos := testUTXOs(4, 1, 3)
+	tests := []struct {
+		selector CoinSelector
+		first    Amount
+	}{
+		{LargestFirstSelector{}, 4},
+		{SmallestFirstSelector{}, 1},
+		{OldestFirstSelector{}, 4},
+	}
+	for _, test := range tests {
+		got, err := test.selector.Select(utxos, 2)
+		if err != nil {
+			t.Errorf("%T.Select error: %v", test.selector, err)
+			continue
+		}
+		if got[0].Output.Value != test.first {
+			t.Errorf("%T selected %d first, want %d", test.selector, got[0].Output.Value, test.first)
+		}
+	}
+}
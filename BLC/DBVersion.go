@@ -0,0 +1,89 @@
+package BLC
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+// 数据库版本管理文件
+// meta表中记录数据库的数据格式版本：
+//   0 -- 早期版本，输出金额为整数个币(创世区块奖励为10)，没有版本记录
+//   1 -- 输出金额以最小单位存储(1个币 = 10^8个最小单位，见Amount.go)
+// 金额单位参与交易哈希与区块哈希的计算，早期版本的区块无法迁移，打开时报错，需要删除数据库后重新创建区块链
+
+// 表名称
+const metaTableName = "meta"
+
+// 数据库版本的键
+var dbVersionKey = []byte("version")
+
+// 当前数据库版本
+const dbVersion = 1
+
+// 检查数据库版本，在打开数据库的写事务中调用
+// 没有版本记录时根据创世区块的奖励判断：空链或奖励为当前单位时写入当前版本，否则为早期版本
+func checkDBVersion(tx *bolt.Tx) error {
+	meta, err := tx.CreateBucketIfNotExists([]byte(metaTableName))
+	if err != nil {
+		return err
+	}
+	if data := meta.Get(dbVersionKey); data != nil {
+		if len(data) != 4 {
+			return fmt.Errorf("invalid db version record [%x]", data)
+		}
+		if version := binary.BigEndian.Uint32(data); version != dbVersion {
+			return fmt.Errorf("db version [%d] is not supported, want [%d]", version, dbVersion)
+		}
+		return nil
+	}
+
+	genesis, err := genesisBlockTx(tx)
+	if err != nil {
+		return err
+	}
+	if genesis != nil {
+		coinbase := genesis.Txs[0]
+		if len(coinbase.Vouts) != 1 {
+			return fmt.Errorf("invalid genesis block [%x]", genesis.Hash)
+		}
+		if reward := coinbase.Vouts[0].Value; reward != coinbaseReward {
+			return fmt.Errorf("db version [0] is not supported: amounts are stored in whole coins (genesis reward [%d]), "+
+				"remove the db and run createblockchain again", int64(reward))
+		}
+	}
+	var data [4]byte
+	binary.BigEndian.PutUint32(data[:], dbVersion)
+	return meta.Put(dbVersionKey, data[:])
+}
+
+// 在数据库事务中沿最新区块找到创世区块，空链返回nil
+func genesisBlockTx(tx *bolt.Tx) (*Block, error) {
+	b := tx.Bucket([]byte(blockTableName))
+	if b == nil {
+		return nil, nil
+	}
+	tip := b.Get([]byte("l"))
+	if tip == nil {
+		return nil, nil
+	}
+	hash := tip
+	for {
+		blockBytes := b.Get(hash)
+		if blockBytes == nil {
+			return nil, fmt.Errorf("block [%x] not found", hash)
+		}
+		block, err := DecodeBlock(blockBytes)
+		if err != nil {
+			return nil, err
+		}
+		if isNullHash(block.PrevBlockHash) {
+			if len(block.Txs) == 0 {
+				return nil, fmt.Errorf("genesis block [%x] has no transactions", block.Hash)
+			}
+			return block, nil
+		}
+		hash = block.PrevBlockHash
+	}
+}
@@ -0,0 +1,112 @@
+package BLC
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+// 临时目录中的数据库，测试结束时关闭
+func newTestDB(t *testing.T) *bolt.DB {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "block.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// 写入以block为最新区块的区块表
+func putTestBlocks(t *testing.T, db *bolt.DB, blocks ...*Block) {
+	t.Helper()
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(blockTableName))
+		if err != nil {
+			return err
+		}
+		for _, block := range blocks {
+			if err := b.Put(block.Hash, block.Serialize()); err != nil {
+				return err
+			}
+		}
+		return b.Put([]byte("l"), blocks[len(blocks)-1].Hash)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readDBVersion(t *testing.T, db *bolt.DB) []byte {
+	t.Helper()
+	var data []byte
+	db.View(func(tx *bolt.Tx) error {
+		if meta := tx.Bucket([]byte(metaTableName)); meta != nil {
+			data = append(data, meta.Get(dbVersionKey)...)
+		}
+		return nil
+	})
+	return data
+}
+
+func TestCheckDBVersionNewChain(t *testing.T) {
+	db := newTestDB(t)
+	putTestBlocks(t, db, CreateGenesisBlock([]*Transaction{NewCoinbaseTransaction("alice")}))
+	if err := db.Update(checkDBVersion); err != nil {
+		t.Fatal(err)
+	}
+	if data := readDBVersion(t, db); len(data) != 4 || binary.BigEndian.Uint32(data) != dbVersion {
+		t.Fatalf("db version record = %x, want %d", data, dbVersion)
+	}
+	// 已有版本记录时不再检查创世区块
+	if err := db.Update(checkDBVersion); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckDBVersionEmptyChain(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.Update(checkDBVersion); err != nil {
+		t.Fatal(err)
+	}
+	if data := readDBVersion(t, db); len(data) != 4 {
+		t.Fatalf("empty db got no version record")
+	}
+}
+
+// 早期版本的数据库中创世区块奖励为整数10
+func TestCheckDBVersionRejectsWholeCoinAmounts(t *testing.T) {
+	db := newTestDB(t)
+	coinbase := NewCoinbaseTransaction("alice")
+	coinbase.Vouts[0].Value = 10
+	coinbase.HashTransaction()
+	genesis := CreateGenesisBlock([]*Transaction{coinbase})
+	putTestBlocks(t, db, genesis, NewBlock(2, genesis.Hash, nil))
+	err := db.Update(checkDBVersion)
+	if err == nil || !strings.Contains(err.Error(), "db version [0]") {
+		t.Fatalf("checkDBVersion error = %v, want db version [0] error", err)
+	}
+	if data := readDBVersion(t, db); data != nil {
+		t.Fatalf("old db got a version record %x", data)
+	}
+}
+
+func TestCheckDBVersionRejectsUnknownVersion(t *testing.T) {
+	db := newTestDB(t)
+	err := db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucket([]byte(metaTableName))
+		if err != nil {
+			return err
+		}
+		return meta.Put(dbVersionKey, []byte{0, 0, 0, 2})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Update(checkDBVersion); err == nil {
+		t.Fatal("checkDBVersion accepted db version 2")
+	}
+}
@@ -0,0 +1,157 @@
+package BLC
+
+import (
+	"sync"
+)
+
+// 区块链事件管理文件
+// 通过BlockChain.Subscribe订阅区块接入、断开主链，交易进入、移出交易池的事件
+// 事件在单独的goroutine中按发生顺序逐个分发给所有订阅者，前一个事件处理完后才分发下一个
+// 发布事件只追加到队列，不会阻塞区块链操作；订阅者处理过慢时队列增长，处理函数应尽快返回
+// 处理函数在分发goroutine中运行，访问区块链时需自行加锁(节点中为Node.chainMutex)
+
+// 事件类型
+type EventType int
+
+const (
+	EventBlockConnected    EventType = iota // 区块接入主链
+	EventBlockDisconnected                  // 区块断开主链(切换分叉)
+	EventTxAccepted                         // 交易进入交易池
+	EventTxRemoved                          // 交易移出交易池
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventBlockConnected:
+		return "blockconnected"
+	case EventBlockDisconnected:
+		return "blockdisconnected"
+	case EventTxAccepted:
+		return "txaccepted"
+	case EventTxRemoved:
+		return "txremoved"
+	}
+	return "unknown"
+}
+
+// 事件
+type Event struct {
+	Type  EventType
+	Block *Block       // 接入或断开的区块；TxRemoved时为打包该交易的区块，交易因失效被移除时为nil
+	Tx    *Transaction // 进入或移出交易池的交易
+}
+
+// 事件处理函数
+type EventHandler func(event *Event)
+
+// 订阅
+type EventSubscription struct {
+	bus     *EventBus
+	handler EventHandler
+}
+
+// 取消订阅，之后不再收到新的事件
+func (subscription *EventSubscription) Unsubscribe() {
+	bus := subscription.bus
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	for index, s := range bus.subscriptions {
+		if s == subscription {
+			bus.subscriptions = append(bus.subscriptions[:index:index], bus.subscriptions[index+1:]...)
+			break
+		}
+	}
+}
+
+// 事件总线
+type EventBus struct {
+	mutex         sync.Mutex
+	subscriptions []*EventSubscription
+	queue         []*Event
+	dispatching   bool // 分发goroutine是否在运行，队列为空时退出，有新事件时重新启动
+}
+
+// 订阅事件
+func (bus *EventBus) Subscribe(handler EventHandler) *EventSubscription {
+	subscription := &EventSubscription{bus: bus, handler: handler}
+	bus.mutex.Lock()
+	bus.subscriptions = append(bus.subscriptions, subscription)
+	bus.mutex.Unlock()
+	return subscription
+}
+
+// 是否有订阅者
+func (bus *EventBus) hasSubscribers() bool {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	return len(bus.subscriptions) > 0
+}
+
+// 发布事件，没有订阅者时丢弃
+func (bus *EventBus) publish(event *Event) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	if len(bus.subscriptions) == 0 {
+		return
+	}
+	bus.queue = append(bus.queue, event)
+	if !bus.dispatching {
+		bus.dispatching = true
+		go bus.dispatch()
+	}
+}
+
+// 按顺序分发队列中的事件
+func (bus *EventBus) dispatch() {
+	for {
+		bus.mutex.Lock()
+		if len(bus.queue) == 0 {
+			bus.dispatching = false
+			bus.mutex.Unlock()
+			return
+		}
+		event := bus.queue[0]
+		bus.queue[0] = nil
+		bus.queue = bus.queue[1:]
+		subscriptions := bus.subscriptions
+		bus.mutex.Unlock()
+
+		for _, subscription := range subscriptions {
+			subscription.handler(event)
+		}
+	}
+}
+
+// 订阅区块链事件
+func (blockchain *BlockChain) Subscribe(handler EventHandler) *EventSubscription {
+	return blockchain.eventBus().Subscribe(handler)
+}
+
+// 区块链的事件总线，首次使用时创建
+func (blockchain *BlockChain) eventBus() *EventBus {
+	blockchain.eventMutex.Lock()
+	defer blockchain.eventMutex.Unlock()
+	if blockchain.events == nil {
+		blockchain.events = &EventBus{}
+	}
+	return blockchain.events
+}
+
+// 发布区块链事件
+func (blockchain *BlockChain) publish(event *Event) {
+	blockchain.eventBus().publish(event)
+}
+
+// 最新区块从oldTip切换到当前最新区块：依次发布断开与接入主链的区块
+func (blockchain *BlockChain) publishTipChanged(oldTip []byte) {
+	if !blockchain.eventBus().hasSubscribers() {
+		return
+	}
+	disconnected, connected := blockchain.ChainChanges(oldTip, blockchain.Tip)
+	for _, block := range disconnected {
+		blockchain.publish(&Event{Type: EventBlockDisconnected, Block: block})
+	}
+	for _, block := range connected {
+		blockchain.publish(&Event{Type: EventBlockConnected, Block: block})
+	}
+}
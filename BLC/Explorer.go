@@ -0,0 +1,211 @@
+package BLC
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 区块浏览器页面管理文件
+// 服务端使用html/template渲染页面：
+//   /                    最新区块
+//   /block/{hash}        区块详情
+//   /block-height/{n}    主链上指定高度的区块
+//   /tx/{txid}           交易详情，输入链接到引用的交易，地址链接到地址页面
+//   /address/{address}   地址余额与交易历史
+//   /search?q=           按高度、区块哈希、交易哈希或地址查找
+
+// 首页显示的区块数量
+const explorerLatestBlocks = 20
+
+// 页面模板
+var explorerTemplates = template.Must(template.New("explorer").Funcs(template.FuncMap{
+	"time": func(timestamp int64) string {
+		return time.Unix(timestamp, 0).Format("2006-01-02 15:04:05")
+	},
+	"short": func(hash string) string {
+		if len(hash) > 16 {
+			return hash[:16] + "..."
+		}
+		return hash
+	},
+	"path": url.PathEscape,
+}).Parse(explorerLayout))
+
+func init() {
+	template.Must(explorerTemplates.New("txio").Parse(explorerTxIO))
+	template.Must(explorerTemplates.New("index").Parse(explorerIndex))
+	template.Must(explorerTemplates.New("block").Parse(explorerBlock))
+	template.Must(explorerTemplates.New("tx").Parse(explorerTx))
+	template.Must(explorerTemplates.New("address").Parse(explorerAddress))
+	template.Must(explorerTemplates.New("error").Parse(explorerError))
+}
+
+// 页面数据
+type explorerPage struct {
+	Title string
+	Data  interface{}
+}
+
+// 注册浏览器页面
+func (server *HTTPServer) registerExplorer(mux *http.ServeMux) {
+	mux.HandleFunc("/", server.handleIndex)
+	mux.HandleFunc("/block/", func(w http.ResponseWriter, r *http.Request) {
+		result, err := server.block(strings.TrimPrefix(r.URL.Path, "/block/"))
+		server.render(w, "block", "区块", result, err)
+	})
+	mux.HandleFunc("/block-height/", func(w http.ResponseWriter, r *http.Request) {
+		result, err := server.blockAtHeight(strings.TrimPrefix(r.URL.Path, "/block-height/"))
+		server.render(w, "block", "区块", result, err)
+	})
+	mux.HandleFunc("/tx/", func(w http.ResponseWriter, r *http.Request) {
+		result, err := server.transaction(strings.TrimPrefix(r.URL.Path, "/tx/"))
+		server.render(w, "tx", "交易", result, err)
+	})
+	mux.HandleFunc("/address/", func(w http.ResponseWriter, r *http.Request) {
+		result, err := server.address(strings.TrimPrefix(r.URL.Path, "/address/"))
+		server.render(w, "address", "地址", result, err)
+	})
+	mux.HandleFunc("/search", server.handleSearch)
+}
+
+// 首页：最新区块
+func (server *HTTPServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		server.render(w, "", "", nil, notFound("page not found"))
+		return
+	}
+	server.render(w, "index", "最新区块", server.latestBlocks(explorerLatestBlocks), nil)
+}
+
+// 查找：数字为区块高度，64位hex依次尝试区块哈希与交易哈希，其他按地址处理
+func (server *HTTPServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	target := "/"
+	if query != "" {
+		target = "/address/" + url.PathEscape(query)
+		if _, err := strconv.ParseInt(query, 10, 64); err == nil {
+			target = "/block-height/" + query
+		} else if len(query) == 64 {
+			if _, err := server.block(query); err == nil {
+				target = "/block/" + query
+			} else if _, err := server.transaction(query); err == nil {
+				target = "/tx/" + query
+			}
+		}
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// 渲染页面，err不为空时渲染错误页面
+func (server *HTTPServer) render(w http.ResponseWriter, name, title string, data interface{}, err error) {
+	status := http.StatusOK
+	if err != nil {
+		status = httpStatus(err)
+		name, title, data = "error", "错误", err.Error()
+	}
+	var buf bytes.Buffer
+	if err := explorerTemplates.ExecuteTemplate(&buf, name, &explorerPage{title, data}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+const explorerLayout = `{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} - 区块浏览器</title>
+<style>
+body { font-family: sans-serif; margin: 0 auto; max-width: 1000px; padding: 0 16px; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 16px; }
+th, td { border-bottom: 1px solid #ddd; padding: 6px; text-align: left; font-size: 14px; }
+td.hash { font-family: monospace; word-break: break-all; }
+header { display: flex; justify-content: space-between; align-items: center; border-bottom: 2px solid #333; }
+a { color: #0645ad; text-decoration: none; }
+</style>
+</head>
+<body>
+<header>
+<h2><a href="/">区块浏览器</a></h2>
+<form action="/search"><input name="q" size="50" placeholder="区块高度 / 区块哈希 / 交易哈希 / 地址"> <button>查找</button></form>
+</header>
+<h3>{{.Title}}</h3>
+{{end}}
+{{define "footer"}}</body>
+</html>
+{{end}}`
+
+const explorerIndex = `{{template "header" .}}
+<table>
+<tr><th>高度</th><th>哈希</th><th>时间</th><th>交易数量</th></tr>
+{{range .Data}}<tr>
+<td><a href="/block/{{.Hash}}">{{.Height}}</a></td>
+<td class="hash"><a href="/block/{{.Hash}}">{{.Hash}}</a></td>
+<td>{{time .Time}}</td>
+<td>{{len .TxIDs}}</td>
+</tr>{{else}}<tr><td colspan="4">区块链为空</td></tr>{{end}}
+</table>
+{{template "footer" .}}`
+
+const explorerBlock = `{{template "header" .}}{{with .Data}}
+<table>
+<tr><th>哈希</th><td class="hash">{{.Hash}}</td></tr>
+<tr><th>上一区块</th><td class="hash">{{if .PreviousBlockHash}}<a href="/block/{{.PreviousBlockHash}}">{{.PreviousBlockHash}}</a>{{else}}无(创世区块){{end}}</td></tr>
+<tr><th>高度</th><td>{{.Height}}</td></tr>
+<tr><th>时间</th><td>{{time .Time}}</td></tr>
+<tr><th>Nonce</th><td>{{.Nonce}}</td></tr>
+<tr><th>确认数</th><td>{{if lt .Confirmations 0}}不在主链上{{else}}{{.Confirmations}}{{end}}</td></tr>
+</table>
+<h3>交易({{len .Txs}})</h3>
+{{range .Txs}}{{template "txio" .}}{{end}}
+{{end}}{{template "footer" .}}`
+
+const explorerTx = `{{template "header" .}}{{with .Data}}
+<table>
+<tr><th>交易哈希</th><td class="hash">{{.TxID}}</td></tr>
+{{if .BlockHash}}<tr><th>区块</th><td class="hash"><a href="/block/{{.BlockHash}}">{{.BlockHash}}</a></td></tr>
+<tr><th>高度</th><td>{{.Height}}</td></tr>
+<tr><th>时间</th><td>{{time .Time}}</td></tr>
+<tr><th>确认数</th><td>{{.Confirmations}}</td></tr>
+{{else}}<tr><th>状态</th><td>尚在交易池中</td></tr>{{end}}
+</table>
+{{template "txio" .}}
+{{end}}{{template "footer" .}}`
+
+const explorerAddress = `{{template "header" .}}{{with .Data}}
+<table>
+<tr><th>地址</th><td class="hash">{{.Address}}</td></tr>
+<tr><th>余额</th><td>{{.Balance}}</td></tr>
+</table>
+<h3>交易历史({{len .Txs}})</h3>
+<table>
+<tr><th>交易哈希</th><th>高度</th><th>时间</th><th>转入</th><th>转出</th></tr>
+{{range .Txs}}<tr>
+<td class="hash"><a href="/tx/{{.TxID}}">{{short .TxID}}</a></td>
+<td><a href="/block/{{.BlockHash}}">{{.Height}}</a></td>
+<td>{{time .Time}}</td>
+<td>{{.Received}}</td>
+<td>{{.Sent}}</td>
+</tr>{{else}}<tr><td colspan="5">没有相关交易</td></tr>{{end}}
+</table>
+{{end}}{{template "footer" .}}`
+
+const explorerError = `{{template "header" .}}<p>{{.Data}}</p>{{template "footer" .}}`
+
+// 交易的输入与输出
+const explorerTxIO = `<table>
+<tr><th colspan="2" class="hash"><a href="/tx/{{.TxID}}">{{.TxID}}</a></th></tr>
+<tr><td width="50%" valign="top">
+{{range .Vin}}{{if .Coinbase}}<div>系统奖励(coinbase)</div>{{else}}<div><a href="/tx/{{.TxID}}">{{short .TxID}}</a>:{{.Vout}} <a href="/address/{{path .ScriptSig}}">{{.ScriptSig}}</a></div>{{end}}{{end}}
+</td><td valign="top">
+{{range .Vout}}<div>#{{.N}} {{if .Data}}数据：{{.Data}}{{else}}<a href="/address/{{path .Address}}">{{.Address}}</a> {{.Value}}{{end}}</div>{{end}}
+</td></tr>
+</table>`
@@ -0,0 +1,277 @@
+package BLC
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// REST接口与区块浏览器管理文件
+// 只读服务，无需认证，通过startnode -http启动：
+//   GET /api/block/{hash}         区块详情(包含完整交易)
+//   GET /api/block-height/{n}     主链上指定高度的区块
+//   GET /api/tx/{txid}            交易详情，包括交易池中的交易
+//   GET /api/address/{address}    地址余额与交易历史
+//   GET /api/events?address=...   以server-sent events推送通知，address可指定多个关注的地址
+//   GET /metrics                  Prometheus格式的监控指标，见Metrics.go
+// 其他路径为区块浏览器页面，见Explorer.go
+
+// 通知推送连接的保活间隔
+const eventKeepAliveInterval = 15 * time.Second
+
+// HTTP错误，status为返回的状态码
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func badRequest(message string) error {
+	return &httpError{http.StatusBadRequest, message}
+}
+
+func notFound(message string) error {
+	return &httpError{http.StatusNotFound, message}
+}
+
+// 错误对应的状态码
+func httpStatus(err error) int {
+	if e, ok := err.(*httpError); ok {
+		return e.status
+	}
+	return http.StatusInternalServerError
+}
+
+// REST接口与区块浏览器服务
+type HTTPServer struct {
+	node   *Node
+	addr   string // 监听地址，主机为空时只监听本机
+	server *http.Server
+	quit   chan struct{}
+
+	utxoCache utxoCountCache
+}
+
+// 创建HTTP服务
+func NewHTTPServer(node *Node, addr string) *HTTPServer {
+	return &HTTPServer{node: node, addr: addr, quit: make(chan struct{})}
+}
+
+// 启动HTTP服务
+func (server *HTTPServer) Start() error {
+	listener, err := listenLocal(server.addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/", server.handleAPI)
+	mux.HandleFunc("/metrics", server.handleMetrics)
+	server.registerExplorer(mux)
+	server.server = &http.Server{Handler: mux, ReadTimeout: 30 * time.Second}
+	go server.server.Serve(listener)
+	rpcLog.Infof("区块浏览器已启动，地址 [http://%s/]\n", listener.Addr())
+	return nil
+}
+
+// 停止HTTP服务
+func (server *HTTPServer) Stop() {
+	if server.server == nil {
+		return
+	}
+	// 先结束通知推送，否则Shutdown会等待推送连接超时
+	close(server.quit)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.server.Shutdown(ctx)
+}
+
+// 处理REST请求
+func (server *HTTPServer) handleAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	kind, arg := splitPath(strings.TrimPrefix(r.URL.Path, "/api/"))
+	if kind == "events" {
+		server.handleEvents(w, r)
+		return
+	}
+	var result interface{}
+	var err error
+	switch kind {
+	case "block":
+		result, err = server.block(arg)
+	case "block-height":
+		result, err = server.blockAtHeight(arg)
+	case "tx":
+		result, err = server.transaction(arg)
+	case "address":
+		result, err = server.address(arg)
+	default:
+		err = notFound("unknown endpoint")
+	}
+	if err != nil {
+		writeJSON(w, httpStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// 将路径拆分为类型与参数，例如block/abc -> block, abc
+func splitPath(path string) (string, string) {
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// 根据哈希查询区块
+func (server *HTTPServer) block(hashStr string) (*BlockResult, error) {
+	hash, err := hex.DecodeString(hashStr)
+	if err != nil || len(hash) != 32 {
+		return nil, badRequest("invalid block hash")
+	}
+	var result *BlockResult
+	server.node.withChain(func(blockchain *BlockChain) {
+		if block := blockchain.GetBlock(hash); block != nil {
+			result = NewBlockResult(block, blockchain.Height(), true)
+			if !blockchain.IsMainChain(block) {
+				result.Confirmations = -1
+			}
+		}
+	})
+	if result == nil {
+		return nil, notFound("block not found")
+	}
+	return result, nil
+}
+
+// 根据高度查询主链上的区块
+func (server *HTTPServer) blockAtHeight(heightStr string) (*BlockResult, error) {
+	height, err := strconv.ParseInt(heightStr, 10, 64)
+	if err != nil {
+		return nil, badRequest("invalid block height")
+	}
+	var result *BlockResult
+	server.node.withChain(func(blockchain *BlockChain) {
+		if block := blockchain.GetBlockByHeight(height); block != nil {
+			result = NewBlockResult(block, blockchain.Height(), true)
+		}
+	})
+	if result == nil {
+		return nil, notFound("block height out of range")
+	}
+	return result, nil
+}
+
+// 根据交易哈希查询交易，先查交易池，再查区块链
+func (server *HTTPServer) transaction(txid string) (*TxResult, error) {
+	txHash, err := hex.DecodeString(txid)
+	if err != nil || len(txHash) != 32 {
+		return nil, badRequest("invalid txid")
+	}
+	if tx := server.node.mempool.Get(txHash); tx != nil {
+		return NewTxResult(tx, nil, 0), nil
+	}
+	var result *TxResult
+	server.node.withChain(func(blockchain *BlockChain) {
+		if blockchain.IsEmpty() {
+			return
+		}
+		if tx, block := blockchain.FindTransaction(txHash); tx != nil {
+			result = NewTxResult(tx, block, blockchain.Height())
+		}
+	})
+	if result == nil {
+		return nil, notFound("transaction not found")
+	}
+	return result, nil
+}
+
+// 查询地址余额与交易历史
+func (server *HTTPServer) address(address string) (*AddressResult, error) {
+	if address == "" {
+		return nil, badRequest("address is required")
+	}
+	var result *AddressResult
+	server.node.withChain(func(blockchain *BlockChain) {
+		if blockchain.IsEmpty() {
+			result = NewAddressResult(address, 0, nil)
+			return
+		}
+		result = NewAddressResult(address, blockchain.getBalance(address), blockchain.AddressHistory(address))
+	})
+	return result, nil
+}
+
+// 主链上最新的count个区块，从最新区块开始
+func (server *HTTPServer) latestBlocks(count int) []*BlockResult {
+	var results []*BlockResult
+	server.node.withChain(func(blockchain *BlockChain) {
+		tipHeight := blockchain.Height()
+		block := blockchain.TipBlock()
+		for block != nil && len(results) < count {
+			results = append(results, NewBlockResult(block, tipHeight, false))
+			block = blockchain.GetBlock(block.PrevBlockHash)
+		}
+	})
+	return results
+}
+
+// 以server-sent events推送通知，每条通知的事件名为通知类型，数据为JSON
+// 订阅因处理过慢被取消时结束推送，客户端可重新连接
+func (server *HTTPServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming unsupported"})
+		return
+	}
+	subscription := server.node.Notifier().Subscribe(r.URL.Query()["address"])
+	defer server.node.Notifier().Unsubscribe(subscription)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, ": subscribed\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case notification, ok := <-subscription.C:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(notification)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", notification.Type, data)
+		case <-keepAlive.C:
+			fmt.Fprintf(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		case <-server.quit:
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,129 @@
+package BLC
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+// 主链高度索引管理文件
+// 记录主链上每个高度的区块哈希，在更新最新区块的数据库事务中与地址索引一起更新，
+// 按高度查找区块、判断区块是否在主链上时无需从最新区块逐个向前读取区块
+// 存储结构(表heightindex)：
+//   tip -- 索引对应的最新区块的高度(8字节)+哈希
+//   高度(8字节) -> 主链上该高度的区块哈希
+// 早期版本创建的数据库没有高度索引，首次查询时从最新区块向前建立
+
+// 表名称
+const heightIndexTableName = "heightindex"
+
+// 索引对应的最新区块
+var heightIndexTipKey = []byte("tip")
+
+func heightIndexKey(height int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(height))
+	return key
+}
+
+// 将高度索引更新到newTip：从newTip向前写入各高度的区块哈希，直到遇到索引中相同的区块(分叉点)，
+// 再删除高于newTip的索引项
+// 在更新最新区块的数据库事务中调用；高度索引不存在时不做处理
+func updateHeightIndex(tx *bolt.Tx, newTip []byte) error {
+	index := tx.Bucket([]byte(heightIndexTableName))
+	if index == nil {
+		return nil
+	}
+	b := tx.Bucket([]byte(blockTableName))
+	if b == nil {
+		return errors.New("block bucket not found")
+	}
+	reader := &txBlockReader{bucket: b, cache: make(map[string]*Block)}
+
+	block, err := reader.get(newTip)
+	if err != nil {
+		return err
+	}
+	tipHeight := block.Height
+	for block != nil && !bytes.Equal(index.Get(heightIndexKey(block.Height)), block.Hash) {
+		if err := index.Put(heightIndexKey(block.Height), block.Hash); err != nil {
+			return err
+		}
+		if block, err = reader.parent(block); err != nil {
+			return err
+		}
+	}
+
+	// 高度键为8字节，tip键排在所有高度键之后
+	var stale [][]byte
+	c := index.Cursor()
+	for k, _ := c.Seek(heightIndexKey(tipHeight + 1)); k != nil && len(k) == 8; k, _ = c.Next() {
+		stale = append(stale, append([]byte{}, k...))
+	}
+	for _, k := range stale {
+		if err := index.Delete(k); err != nil {
+			return err
+		}
+	}
+	return index.Put(heightIndexTipKey, append(heightIndexKey(tipHeight), newTip...))
+}
+
+// 在只读事务中通过高度索引读取主链，索引落后于最新区块时先更新索引
+// blockchain的最新区块不是数据库中的最新区块(例如验证分叉区块时基于父区块的视图)时不使用索引，返回false
+func (blockchain *BlockChain) viewHeightIndex(fn func(index *bolt.Bucket, tipHeight int64) error) (bool, error) {
+	if blockchain.IsEmpty() {
+		return false, nil
+	}
+	var indexed, mainChain bool
+	view := func(tx *bolt.Tx) error {
+		index := tx.Bucket([]byte(heightIndexTableName))
+		if index != nil {
+			if tip := index.Get(heightIndexTipKey); len(tip) > 8 && bytes.Equal(tip[8:], blockchain.Tip) {
+				indexed = true
+				return fn(index, int64(binary.BigEndian.Uint64(tip[:8])))
+			}
+		}
+		b := tx.Bucket([]byte(blockTableName))
+		mainChain = b != nil && bytes.Equal(b.Get([]byte("l")), blockchain.Tip)
+		return nil
+	}
+	if err := blockchain.DB.View(view); err != nil || indexed || !mainChain {
+		return indexed, err
+	}
+
+	err := blockchain.DB.Update(func(tx *bolt.Tx) error {
+		// 不持有区块链锁的视图在读取后最新区块可能已变化，此时不更新索引
+		if !bytes.Equal(tx.Bucket([]byte(blockTableName)).Get([]byte("l")), blockchain.Tip) {
+			return nil
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(heightIndexTableName)); err != nil {
+			return err
+		}
+		return updateHeightIndex(tx, blockchain.Tip)
+	})
+	if err != nil {
+		return false, fmt.Errorf("update the height index failed: %v", err)
+	}
+	err = blockchain.DB.View(view)
+	return indexed, err
+}
+
+// 主链上指定高度的区块哈希，不存在时返回nil
+// 不能使用高度索引时返回false，由调用方从最新区块向前查找
+func (blockchain *BlockChain) mainChainHash(height int64) ([]byte, bool) {
+	var hash []byte
+	indexed, err := blockchain.viewHeightIndex(func(index *bolt.Bucket, tipHeight int64) error {
+		if v := index.Get(heightIndexKey(height)); v != nil {
+			hash = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		chainLog.Warnf("读取高度索引失败：%v\n", err)
+		return nil, false
+	}
+	return hash, indexed
+}
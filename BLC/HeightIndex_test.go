@@ -0,0 +1,108 @@
+package BLC
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+// 检查高度索引与从最新区块向前遍历得到的主链一致，且没有高于最新区块的索引项
+func checkHeightIndex(t *testing.T, blockchain *BlockChain) {
+	t.Helper()
+	tip := blockchain.TipBlock()
+	if got := blockchain.Height(); got != tip.Height {
+		t.Fatalf("Height() = %d, want %d", got, tip.Height)
+	}
+	for block := tip; block != nil; block = blockchain.GetBlock(block.PrevBlockHash) {
+		main := blockchain.GetBlockByHeight(block.Height)
+		if main == nil || !bytes.Equal(main.Hash, block.Hash) {
+			t.Fatalf("block at height %d is not the main chain block %x", block.Height, block.Hash)
+		}
+		if !blockchain.IsMainChain(block) {
+			t.Fatalf("main chain block at height %d is not on the main chain", block.Height)
+		}
+	}
+	if block := blockchain.GetBlockByHeight(tip.Height + 1); block != nil {
+		t.Fatalf("GetBlockByHeight(%d) = %x above the tip", tip.Height+1, block.Hash)
+	}
+	var keys int
+	err := blockchain.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(heightIndexTableName)).ForEach(func(k, v []byte) error {
+			keys++
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys != int(tip.Height)+1 {
+		t.Fatalf("height index has %d keys, want %d heights and the tip", keys, tip.Height)
+	}
+}
+
+func TestHeightIndexReorg(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+	genesis := blockchain.TipBlock()
+
+	a2 := newTestBlock(t, blockchain, genesis, "alice", "bob", CoinUnit)
+	acceptTestBlock(t, blockchain, a2)
+	a3 := newTestBlock(t, blockchain, a2, "alice", "bob", CoinUnit)
+	acceptTestBlock(t, blockchain, a3)
+	checkHeightIndex(t, blockchain)
+
+	b2 := newTestBlock(t, blockchain, genesis, "alice", "carol", CoinUnit)
+	acceptTestBlock(t, blockchain, b2)
+	if blockchain.IsMainChain(b2) {
+		t.Fatal("side chain block is on the main chain")
+	}
+	b3 := newTestBlock(t, blockchain, b2, "alice", "carol", CoinUnit)
+	acceptTestBlock(t, blockchain, b3)
+	b4 := newTestBlock(t, blockchain, b3, "alice", "carol", CoinUnit)
+	if !acceptTestBlock(t, blockchain, b4) {
+		t.Fatal("longer side chain did not become the main chain")
+	}
+	checkHeightIndex(t, blockchain)
+	if blockchain.IsMainChain(a2) || blockchain.IsMainChain(a3) {
+		t.Fatal("blocks of the old chain are still on the main chain")
+	}
+
+	// 基于分叉区块的视图不使用高度索引
+	view := &BlockChain{DB: blockchain.DB, Tip: a3.Hash}
+	if view.Height() != 3 || !view.IsMainChain(a2) || view.IsMainChain(b2) {
+		t.Fatal("view of the old chain uses the height index of the main chain")
+	}
+	if block := view.GetBlockByHeight(2); block == nil || !bytes.Equal(block.Hash, a2.Hash) {
+		t.Fatal("view of the old chain returned a block of the main chain")
+	}
+}
+
+// 早期版本的数据库没有高度索引，首次查询时建立
+func TestHeightIndexBuild(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+	parent := blockchain.TipBlock()
+	for i := 0; i < 3; i++ {
+		parent = newTestBlock(t, blockchain, parent, "alice", "bob", CoinUnit)
+		acceptTestBlock(t, blockchain, parent)
+	}
+	err := blockchain.DB.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte(heightIndexTableName))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHeightIndex(t, blockchain)
+
+	// 索引落后于最新区块时更新
+	err = blockchain.DB.Update(func(tx *bolt.Tx) error {
+		index := tx.Bucket([]byte(heightIndexTableName))
+		if err := index.Delete(heightIndexKey(4)); err != nil {
+			return err
+		}
+		return index.Put(heightIndexTipKey, append(heightIndexKey(3), parent.PrevBlockHash...))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHeightIndex(t, blockchain)
+}
@@ -0,0 +1,330 @@
+package BLC
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 日志管理文件
+// 日志按子系统输出，每条日志带有级别与子系统标签：
+//   2006/01/02 15:04:05 [INF] CHAIN: 消息
+// 级别由低到高为debug、info、warn、error，低于子系统级别的日志被丢弃
+// 日志默认输出到标准错误，与命令的输出(标准输出)分开；可选JSON格式，写入文件时按大小轮转
+
+// 日志级别
+type LogLevel int32
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// 默认日志级别
+const defaultLogLevel = LevelInfo
+
+func (level LogLevel) String() string {
+	switch level {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// 文本格式中的级别标签
+func (level LogLevel) tag() string {
+	switch level {
+	case LevelDebug:
+		return "DBG"
+	case LevelInfo:
+		return "INF"
+	case LevelWarn:
+		return "WRN"
+	case LevelError:
+		return "ERR"
+	}
+	return "???"
+}
+
+// 解析日志级别
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level [%s], want debug|info|warn|error", s)
+}
+
+// 子系统日志
+var (
+	loggers = make(map[string]*Logger)
+
+	chainLog = newLogger("CHAIN") // 区块链与数据库
+	powLog   = newLogger("POW")   // 工作量证明
+	utxoLog  = newLogger("UTXO")  // 交易与未花费输出
+	cliLog   = newLogger("CLI")   // 命令行
+	netLog   = newLogger("NET")   // 节点网络与同步
+	rpcLog   = newLogger("RPC")   // RPC与REST接口
+	hookLog  = newLogger("HOOK")  // 外部命令通知
+)
+
+// 子系统日志
+type Logger struct {
+	subsystem string
+	level     int32 // LogLevel，原子存取
+}
+
+func newLogger(subsystem string) *Logger {
+	logger := &Logger{subsystem: subsystem, level: int32(defaultLogLevel)}
+	loggers[subsystem] = logger
+	return logger
+}
+
+// 所有子系统名称
+func LogSubsystems() []string {
+	var subsystems []string
+	for subsystem := range loggers {
+		subsystems = append(subsystems, subsystem)
+	}
+	sort.Strings(subsystems)
+	return subsystems
+}
+
+// 子系统的日志级别
+func (logger *Logger) Level() LogLevel {
+	return LogLevel(atomic.LoadInt32(&logger.level))
+}
+
+// 设置子系统的日志级别
+func (logger *Logger) SetLevel(level LogLevel) {
+	atomic.StoreInt32(&logger.level, int32(level))
+}
+
+func (logger *Logger) Debugf(format string, args ...interface{}) {
+	logger.output(LevelDebug, format, args...)
+}
+
+func (logger *Logger) Infof(format string, args ...interface{}) {
+	logger.output(LevelInfo, format, args...)
+}
+
+func (logger *Logger) Warnf(format string, args ...interface{}) {
+	logger.output(LevelWarn, format, args...)
+}
+
+func (logger *Logger) Errorf(format string, args ...interface{}) {
+	logger.output(LevelError, format, args...)
+}
+
+// 输出错误日志后panic
+func (logger *Logger) Panicf(format string, args ...interface{}) {
+	message := strings.TrimRight(fmt.Sprintf(format, args...), "\n")
+	logger.output(LevelError, "%s", message)
+	panic(message)
+}
+
+// 输出错误日志后退出程序
+func (logger *Logger) Fatalf(format string, args ...interface{}) {
+	logger.output(LevelError, format, args...)
+	os.Exit(1)
+}
+
+func (logger *Logger) output(level LogLevel, format string, args ...interface{}) {
+	if level < logger.Level() {
+		return
+	}
+	message := strings.TrimRight(fmt.Sprintf(format, args...), "\n")
+	backend.write(time.Now(), level, logger.subsystem, message)
+}
+
+// 日志输出目标
+type logBackend struct {
+	mutex  sync.Mutex
+	writer io.Writer
+	json   bool
+}
+
+var backend = &logBackend{writer: os.Stderr}
+
+// JSON格式的一条日志
+type logRecord struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	Subsystem string `json:"subsystem"`
+	Message   string `json:"msg"`
+}
+
+func (b *logBackend) write(t time.Time, level LogLevel, subsystem, message string) {
+	var line []byte
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.json {
+		line, _ = json.Marshal(&logRecord{t.Format(time.RFC3339Nano), level.String(), subsystem, message})
+		line = append(line, '\n')
+	} else {
+		line = []byte(fmt.Sprintf("%s [%s] %s: %s\n", t.Format("2006/01/02 15:04:05"), level.tag(), subsystem, message))
+	}
+	b.writer.Write(line)
+}
+
+// 日志配置
+type LogConfig struct {
+	Level    string // 日志级别，例如info，或按子系统指定，例如info,NET=debug
+	JSON     bool   // 以JSON格式输出，每行一条
+	File     string // 日志文件，为空时输出到标准错误
+	MaxSize  int64  // 日志文件轮转的大小(字节)
+	MaxFiles int    // 保留的历史日志文件数量
+}
+
+// 日志文件默认轮转大小与保留数量
+const (
+	defaultLogMaxSize  = 10 * 1024 * 1024
+	defaultLogMaxFiles = 3
+)
+
+// 按配置初始化日志
+func InitLogging(config LogConfig) error {
+	if config.Level != "" {
+		if err := SetLogLevels(config.Level); err != nil {
+			return err
+		}
+	}
+	var writer io.Writer = os.Stderr
+	if config.File != "" {
+		file, err := newRotatingFile(config.File, config.MaxSize, config.MaxFiles)
+		if err != nil {
+			return err
+		}
+		writer = file
+	}
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+	backend.writer = writer
+	backend.json = config.JSON
+	return nil
+}
+
+// 设置日志级别，spec由逗号分隔：LEVEL设置所有子系统，SUBSYSTEM=LEVEL设置指定子系统
+// 例如：debug、warn,NET=debug
+func SetLogLevels(spec string) error {
+	levels := make(map[*Logger]LogLevel)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "=") {
+			level, err := ParseLogLevel(part)
+			if err != nil {
+				return err
+			}
+			for _, logger := range loggers {
+				if _, ok := levels[logger]; !ok {
+					levels[logger] = level
+				}
+			}
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		logger, ok := loggers[strings.ToUpper(strings.TrimSpace(kv[0]))]
+		if !ok {
+			return fmt.Errorf("unknown log subsystem [%s], want one of %s", kv[0], strings.Join(LogSubsystems(), "|"))
+		}
+		level, err := ParseLogLevel(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return err
+		}
+		levels[logger] = level
+	}
+	for logger, level := range levels {
+		logger.SetLevel(level)
+	}
+	return nil
+}
+
+// 按大小轮转的日志文件
+// 写入后超过maxSize时，file.N-1依次重命名为file.N，file重命名为file.1，超过maxFiles的历史文件被删除
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func newRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	if maxSize <= 0 {
+		maxSize = defaultLogMaxSize
+	}
+	if maxFiles < 0 {
+		maxFiles = 0
+	}
+	r := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+// 写入日志，调用时持有backend.mutex
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	if r.size >= r.maxSize {
+		r.rotate()
+	}
+	return n, err
+}
+
+func (r *rotatingFile) rotate() {
+	r.file.Close()
+	r.file = nil
+	if r.maxFiles == 0 {
+		os.Remove(r.path)
+		return
+	}
+	os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxFiles))
+	for i := r.maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	os.Rename(r.path, r.path+".1")
+}
@@ -0,0 +1,128 @@
+package BLC
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// 交易池管理文件
+// 保存已验证但尚未打包进区块的交易
+
+// 交易池
+type Mempool struct {
+	mutex sync.RWMutex
+	txs   map[string]*Transaction // 交易哈希(hex)->交易
+	order []string                // 交易的接收顺序
+	bytes int                     // 交易规范编码的总长度
+}
+
+// 创建交易池
+func NewMempool() *Mempool {
+	return &Mempool{txs: make(map[string]*Transaction)}
+}
+
+// 添加交易，交易已存在时返回false
+func (mempool *Mempool) Add(tx *Transaction) bool {
+	mempool.mutex.Lock()
+	defer mempool.mutex.Unlock()
+	key := hex.EncodeToString(tx.TxHash)
+	if _, ok := mempool.txs[key]; ok {
+		return false
+	}
+	mempool.txs[key] = tx
+	mempool.order = append(mempool.order, key)
+	mempool.bytes += len(tx.SerializeRaw())
+	return true
+}
+
+// 根据交易哈希获取交易，不存在时返回nil
+func (mempool *Mempool) Get(txHash []byte) *Transaction {
+	mempool.mutex.RLock()
+	defer mempool.mutex.RUnlock()
+	return mempool.txs[hex.EncodeToString(txHash)]
+}
+
+// 判断交易是否存在
+func (mempool *Mempool) Has(txHash []byte) bool {
+	return mempool.Get(txHash) != nil
+}
+
+// 按接收顺序返回所有交易
+func (mempool *Mempool) Txs() []*Transaction {
+	mempool.mutex.RLock()
+	defer mempool.mutex.RUnlock()
+	var txs []*Transaction
+	for _, key := range mempool.order {
+		txs = append(txs, mempool.txs[key])
+	}
+	return txs
+}
+
+// 交易数量
+func (mempool *Mempool) Count() int {
+	mempool.mutex.RLock()
+	defer mempool.mutex.RUnlock()
+	return len(mempool.txs)
+}
+
+// 交易规范编码的总字节数
+func (mempool *Mempool) Bytes() int {
+	mempool.mutex.RLock()
+	defer mempool.mutex.RUnlock()
+	return mempool.bytes
+}
+
+// 移除指定交易
+func (mempool *Mempool) Remove(txHash []byte) {
+	mempool.mutex.Lock()
+	defer mempool.mutex.Unlock()
+	mempool.remove(hex.EncodeToString(txHash))
+}
+
+// 移除已被区块打包的交易，以及与区块中交易花费相同输出的冲突交易
+// 返回被移除的交易
+func (mempool *Mempool) RemoveBlockTxs(block *Block) []*Transaction {
+	mempool.mutex.Lock()
+	defer mempool.mutex.Unlock()
+
+	spent := make(map[string]bool)
+	included := make(map[string]bool)
+	for _, tx := range block.Txs {
+		included[hex.EncodeToString(tx.TxHash)] = true
+		for _, in := range tx.Vins {
+			spent[fmt.Sprintf("%x:%d", in.TxHash, in.Vout)] = true
+		}
+	}
+
+	var removed []*Transaction
+	for _, key := range append([]string{}, mempool.order...) {
+		tx := mempool.txs[key]
+		conflict := included[key]
+		for _, in := range tx.Vins {
+			if spent[fmt.Sprintf("%x:%d", in.TxHash, in.Vout)] {
+				conflict = true
+			}
+		}
+		if conflict {
+			mempool.remove(key)
+			removed = append(removed, tx)
+		}
+	}
+	return removed
+}
+
+func (mempool *Mempool) remove(key string) {
+	tx, ok := mempool.txs[key]
+	if !ok {
+		return
+	}
+	delete(mempool.txs, key)
+	mempool.bytes -= len(tx.SerializeRaw())
+	for index, k := range mempool.order {
+		if k == key {
+			mempool.order = append(mempool.order[:index], mempool.order[index+1:]...)
+			break
+		}
+	}
+}
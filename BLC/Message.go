@@ -0,0 +1,274 @@
+package BLC
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// 网络消息管理文件
+// 消息格式：
+//   网络标识(uint32) 命令(12字节，不足补0) 负载长度(uint32) 校验和(负载双重sha256的前4字节) 负载
+
+// 协议版本
+const protocolVersion = 1
+
+// 网络标识
+const networkMagic uint32 = 0xb1c0de01
+
+// 命令长度
+const commandLength = 12
+
+// 消息头长度
+const messageHeaderLength = 4 + commandLength + 4 + 4
+
+// 单条消息负载的最大长度
+const maxMessagePayload = 4 * 1024 * 1024
+
+// 单条inv/getdata消息的最大条目数
+const maxInvPerMessage = 50000
+
+// 单次getblocks返回的最大区块数
+const maxBlocksPerInv = 500
+
+// 单条headers消息的最大区块头数
+const maxHeadersPerMsg = 2000
+
+// 单条addr消息的最大地址数
+const maxAddrPerMsg = 1000
+
+// 命令
+const (
+	cmdVersion   = "version"
+	cmdVerack    = "verack"
+	cmdGetBlocks = "getblocks"
+	cmdGetHdrs   = "getheaders"
+	cmdHeaders   = "headers"
+	cmdInv       = "inv"
+	cmdGetData   = "getdata"
+	cmdNotFound  = "notfound"
+	cmdBlock     = "block"
+	cmdTx        = "tx"
+	cmdPing      = "ping"
+	cmdPong      = "pong"
+	cmdGetAddr   = "getaddr"
+	cmdAddr      = "addr"
+)
+
+// 消息过大
+var ErrMessageTooLarge = errors.New("message payload too large")
+
+// 网络消息
+type Message struct {
+	Command string
+	Payload []byte
+}
+
+// 写入一条消息
+func WriteMessage(w io.Writer, command string, payload []byte) error {
+	if len(command) > commandLength {
+		return fmt.Errorf("command [%s] too long", command)
+	}
+	if len(payload) > maxMessagePayload {
+		return ErrMessageTooLarge
+	}
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.BigEndian, networkMagic)
+	var cmd [commandLength]byte
+	copy(cmd[:], command)
+	buffer.Write(cmd[:])
+	binary.Write(&buffer, binary.BigEndian, uint32(len(payload)))
+	buffer.Write(checksum(payload))
+	buffer.Write(payload)
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+// 读取一条消息
+func ReadMessage(r io.Reader) (*Message, error) {
+	var header [messageHeaderLength]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if magic := binary.BigEndian.Uint32(header[:4]); magic != networkMagic {
+		return nil, fmt.Errorf("invalid network magic [%x]", magic)
+	}
+	command := string(bytes.TrimRight(header[4:4+commandLength], "\x00"))
+	length := binary.BigEndian.Uint32(header[4+commandLength:])
+	if length > maxMessagePayload {
+		return nil, ErrMessageTooLarge
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(checksum(payload), header[8+commandLength:]) {
+		return nil, fmt.Errorf("checksum mismatch for command [%s]", command)
+	}
+	return &Message{Command: command, Payload: payload}, nil
+}
+
+// 校验和：负载双重sha256的前4字节
+func checksum(payload []byte) []byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	return second[:4]
+}
+
+// version消息
+type VersionMsg struct {
+	Version    uint32 // 协议版本
+	BestHeight int64  // 最新区块高度
+	AddrFrom   string // 发送方的监听地址
+	Nonce      uint64 // 随机数，用于检测自己连接自己
+}
+
+func (msg *VersionMsg) Serialize() []byte {
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.BigEndian, msg.Version)
+	binary.Write(&buffer, binary.BigEndian, msg.BestHeight)
+	writeVarBytes(&buffer, []byte(msg.AddrFrom))
+	binary.Write(&buffer, binary.BigEndian, msg.Nonce)
+	return buffer.Bytes()
+}
+
+func DeserializeVersionMsg(payload []byte) (*VersionMsg, error) {
+	reader := bytes.NewReader(payload)
+	msg := &VersionMsg{}
+	if err := binary.Read(reader, binary.BigEndian, &msg.Version); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(reader, binary.BigEndian, &msg.BestHeight); err != nil {
+		return nil, err
+	}
+	addrFrom, err := readVarBytes(reader)
+	if err != nil {
+		return nil, err
+	}
+	msg.AddrFrom = string(addrFrom)
+	if err := binary.Read(reader, binary.BigEndian, &msg.Nonce); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// 哈希列表消息，用于getblocks/getheaders(区块定位器)
+func serializeHashes(hashes [][]byte) []byte {
+	var buffer bytes.Buffer
+	writeUvarint(&buffer, uint64(len(hashes)))
+	for _, hash := range hashes {
+		writeVarBytes(&buffer, hash)
+	}
+	return buffer.Bytes()
+}
+
+func deserializeHashes(payload []byte) ([][]byte, error) {
+	reader := bytes.NewReader(payload)
+	count, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+	if count > maxInvPerMessage {
+		return nil, fmt.Errorf("too many hashes [%d]", count)
+	}
+	var hashes [][]byte
+	for i := uint64(0); i < count; i++ {
+		hash, err := readVarBytes(reader)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// 库存类型
+type InvType byte
+
+const (
+	InvTypeTx    InvType = 1
+	InvTypeBlock InvType = 2
+)
+
+// 库存条目：用于inv/getdata消息
+type InvVect struct {
+	Type InvType
+	Hash []byte
+}
+
+func serializeInv(invs []*InvVect) []byte {
+	var buffer bytes.Buffer
+	writeUvarint(&buffer, uint64(len(invs)))
+	for _, inv := range invs {
+		buffer.WriteByte(byte(inv.Type))
+		writeVarBytes(&buffer, inv.Hash)
+	}
+	return buffer.Bytes()
+}
+
+func deserializeInv(payload []byte) ([]*InvVect, error) {
+	reader := bytes.NewReader(payload)
+	count, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+	if count > maxInvPerMessage {
+		return nil, fmt.Errorf("too many inventory vectors [%d]", count)
+	}
+	var invs []*InvVect
+	for i := uint64(0); i < count; i++ {
+		invType, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		hash, err := readVarBytes(reader)
+		if err != nil {
+			return nil, err
+		}
+		invs = append(invs, &InvVect{InvType(invType), hash})
+	}
+	return invs, nil
+}
+
+// 节点地址：用于addr消息
+type NetAddress struct {
+	Addr     string // 节点的监听地址
+	LastSeen int64  // 最近一次得知该节点在线的时间
+}
+
+func serializeAddrs(addrs []*NetAddress) []byte {
+	var buffer bytes.Buffer
+	writeUvarint(&buffer, uint64(len(addrs)))
+	for _, addr := range addrs {
+		writeVarBytes(&buffer, []byte(addr.Addr))
+		binary.Write(&buffer, binary.BigEndian, addr.LastSeen)
+	}
+	return buffer.Bytes()
+}
+
+func deserializeAddrs(payload []byte) ([]*NetAddress, error) {
+	reader := bytes.NewReader(payload)
+	count, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+	if count > maxAddrPerMsg {
+		return nil, fmt.Errorf("too many addresses [%d]", count)
+	}
+	var addrs []*NetAddress
+	for i := uint64(0); i < count; i++ {
+		addr, err := readVarBytes(reader)
+		if err != nil {
+			return nil, err
+		}
+		netAddr := &NetAddress{Addr: string(addr)}
+		if err := binary.Read(reader, binary.BigEndian, &netAddr.LastSeen); err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, netAddr)
+	}
+	return addrs, nil
+}
@@ -0,0 +1,114 @@
+package BLC
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// 监控指标管理文件
+// GET /metrics 以Prometheus文本格式(text/plain; version=0.0.4)输出节点指标，随-http启动
+// 未花费输出数量需要扫描整条主链，按最新区块哈希缓存，最新区块变化后才重新统计；统计时不持有区块链锁
+
+// 指标名前缀
+const metricsNamespace = "bc_"
+
+// 按最新区块缓存的未花费输出数量
+type utxoCountCache struct {
+	mutex sync.Mutex
+	tip   []byte
+	count int
+}
+
+// 一组指标的文本输出
+type metricsWriter struct {
+	buf bytes.Buffer
+}
+
+// 写入一个无标签的指标，kind为gauge或counter
+func (m *metricsWriter) metric(name, kind, help string, value float64) {
+	m.header(name, kind, help)
+	m.sample(name, "", value)
+}
+
+// 写入指标的HELP与TYPE行
+func (m *metricsWriter) header(name, kind, help string) {
+	fmt.Fprintf(&m.buf, "# HELP %s%s %s\n", metricsNamespace, name, help)
+	fmt.Fprintf(&m.buf, "# TYPE %s%s %s\n", metricsNamespace, name, kind)
+}
+
+// 写入一个样本，labels形如direction="inbound"，可为空
+func (m *metricsWriter) sample(name, labels string, value float64) {
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(&m.buf, "%s%s%s %s\n", metricsNamespace, name, labels, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// 输出监控指标
+func (server *HTTPServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var height, lastBlockTime int64
+	var utxoCount int
+	var dbSize int64
+	var view *BlockChain
+	server.node.withChain(func(blockchain *BlockChain) {
+		if info, err := os.Stat(blockchain.DB.Path()); err == nil {
+			dbSize = info.Size()
+		}
+		if blockchain.IsEmpty() {
+			return
+		}
+		height = blockchain.Height()
+		lastBlockTime = blockchain.TipBlock().TimeStamp
+		view = &BlockChain{DB: blockchain.DB, Tip: append([]byte(nil), blockchain.Tip...)}
+	})
+	if view != nil {
+		utxoCount = server.utxoCount(view)
+	}
+	var inbound, outbound int
+	for _, peer := range server.node.Peers() {
+		if peer.Inbound {
+			inbound++
+		} else {
+			outbound++
+		}
+	}
+
+	m := &metricsWriter{}
+	m.metric("chain_height", "gauge", "Height of the best block.", float64(height))
+	m.metric("difficulty", "gauge", "Expected number of hashes to find a block.", Difficulty())
+	m.metric("difficulty_target_bits", "gauge", "Number of leading zero bits required in a block hash.", targetBit)
+	m.metric("last_block_timestamp_seconds", "gauge", "Timestamp of the best block in seconds since the epoch.", float64(lastBlockTime))
+	m.metric("hash_rate", "gauge", "Hashes per second measured during the most recent proof of work.", HashRate())
+	m.metric("pow_nonces_tried_total", "counter", "Total number of nonces tried by proof of work.", float64(NoncesTried()))
+	m.metric("mempool_transactions", "gauge", "Number of transactions in the mempool.", float64(server.node.mempool.Count()))
+	m.metric("mempool_bytes", "gauge", "Total serialized size of transactions in the mempool.", float64(server.node.mempool.Bytes()))
+	m.header("peers", "gauge", "Number of connected peers.")
+	m.sample("peers", `direction="inbound"`, float64(inbound))
+	m.sample("peers", `direction="outbound"`, float64(outbound))
+	m.metric("utxo_count", "gauge", "Number of unspent transaction outputs on the main chain.", float64(utxoCount))
+	m.metric("db_size_bytes", "gauge", "Size of the block database file in bytes.", float64(dbSize))
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(m.buf.Bytes())
+}
+
+// 未花费输出数量，最新区块未变化时使用缓存
+// blockchain为持有区块链锁时复制的最新区块视图，统计期间节点可以继续接入区块
+func (server *HTTPServer) utxoCount(blockchain *BlockChain) int {
+	cache := &server.utxoCache
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if !bytes.Equal(cache.tip, blockchain.Tip) {
+		cache.count = blockchain.UTXOCount()
+		cache.tip = append([]byte(nil), blockchain.Tip...)
+	}
+	return cache.count
+}
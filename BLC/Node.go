@@ -0,0 +1,950 @@
+package BLC
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// 节点管理文件
+// 节点之间通过TCP连接交换区块与交易
+
+const (
+	// 发送ping的间隔
+	pingInterval = time.Minute
+	// 超过该时间没有收到任何消息则断开连接
+	peerTimeout = 3 * pingInterval
+	// 建立连接的超时时间
+	dialTimeout = 5 * time.Second
+	// 挖矿节点检查交易池的间隔
+	mineInterval = time.Second
+	// 默认的主动连接数量
+	defaultTargetOutbound = 8
+	// 检查主动连接数量的间隔
+	connectInterval = 10 * time.Second
+)
+
+// 节点
+type Node struct {
+	ListenAddr string // 本节点的监听地址
+
+	blockchain  *BlockChain
+	mempool     *Mempool
+	syncManager *SyncManager
+	addrManager *AddrManager
+	banManager  *BanManager
+	notifier    *Notifier
+	mine        bool // 是否将交易池中的交易打包成区块
+	// 需要维持的主动连接数量
+	targetOutbound int
+
+	chainMutex sync.Mutex // 保护区块链的写入
+	peerMutex  sync.Mutex
+	peers      map[*Peer]bool
+	nonce      uint64 // 用于检测自己连接自己
+
+	relayMutex sync.Mutex
+	inFlight   map[string]*inventoryRequest // 正在请求的区块与交易
+
+	transport Transport
+	listener  net.Listener
+	quit      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// 创建节点
+func NewNode(listenAddr string, blockchain *BlockChain) *Node {
+	var buf [8]byte
+	rand.Read(buf[:])
+	node := &Node{
+		ListenAddr:     listenAddr,
+		blockchain:     blockchain,
+		mempool:        NewMempool(),
+		peers:          make(map[*Peer]bool),
+		inFlight:       make(map[string]*inventoryRequest),
+		targetOutbound: defaultTargetOutbound,
+		nonce:          binary.BigEndian.Uint64(buf[:]),
+		transport:      TCPTransport,
+		quit:           make(chan struct{}),
+	}
+	node.syncManager = NewSyncManager(node)
+	node.notifier = NewNotifier(blockchain)
+	return node
+}
+
+// 设置网络传输，需在启动前调用
+func (node *Node) SetTransport(transport Transport) {
+	node.transport = transport
+}
+
+// 设置是否挖矿
+func (node *Node) SetMining(mine bool) {
+	node.mine = mine
+}
+
+// 设置需要维持的主动连接数量
+func (node *Node) SetTargetOutbound(count int) {
+	node.targetOutbound = count
+}
+
+// 地址管理器，节点启动后可用
+func (node *Node) AddrManager() *AddrManager {
+	return node.addrManager
+}
+
+// 封禁管理器，节点启动后可用
+func (node *Node) BanManager() *BanManager {
+	return node.banManager
+}
+
+// 交易池
+func (node *Node) Mempool() *Mempool {
+	return node.mempool
+}
+
+// 通知管理器
+func (node *Node) Notifier() *Notifier {
+	return node.notifier
+}
+
+// 同步管理器
+func (node *Node) SyncManager() *SyncManager {
+	return node.syncManager
+}
+
+// 启动节点：监听端口，接受其他节点的连接
+func (node *Node) Start() error {
+	addrManager, err := NewAddrManager(node.blockchain.DB)
+	if err != nil {
+		return fmt.Errorf("load peer addresses failed: %v", err)
+	}
+	node.addrManager = addrManager
+	banManager, err := NewBanManager(node.blockchain.DB)
+	if err != nil {
+		return fmt.Errorf("load ban list failed: %v", err)
+	}
+	node.banManager = banManager
+	listener, err := node.transport.Listen(node.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("listen on [%s] failed: %v", node.ListenAddr, err)
+	}
+	node.listener = listener
+	netLog.Infof("节点已启动，监听地址 [%s]，当前高度 [%d]\n", node.ListenAddr, node.height())
+
+	node.wg.Add(1)
+	go node.acceptLoop()
+	node.wg.Add(1)
+	go node.relayLoop()
+	node.syncManager.Start()
+	node.wg.Add(1)
+	go node.connectionLoop()
+	if node.mine {
+		node.wg.Add(1)
+		go node.mineLoop()
+	}
+	return nil
+}
+
+// 停止节点
+func (node *Node) Stop() {
+	close(node.quit)
+	node.syncManager.Stop()
+	if node.listener != nil {
+		node.listener.Close()
+	}
+	for _, peer := range node.Peers() {
+		peer.Close()
+	}
+	node.wg.Wait()
+	node.notifier.Close()
+}
+
+// 主动连接其他节点，已连接时直接返回
+func (node *Node) Connect(addr string) error {
+	for _, peer := range node.Peers() {
+		if peer.Addr == addr || peer.ListenAddr() == addr {
+			return nil
+		}
+	}
+	if node.banManager.IsBanned(addr) {
+		return fmt.Errorf("address [%s] is banned", addr)
+	}
+	node.addrManager.Attempt(addr)
+	conn, err := node.transport.Dial(addr, dialTimeout)
+	if err != nil {
+		node.addrManager.Failed(addr)
+		return fmt.Errorf("connect to [%s] failed: %v", addr, err)
+	}
+	peer := newPeer(conn, false)
+	peer.Addr = addr
+	node.addPeer(peer)
+	if err := node.sendVersion(peer); err != nil {
+		node.removePeer(peer)
+		return err
+	}
+	node.wg.Add(1)
+	go node.handlePeer(peer)
+	return nil
+}
+
+// 当前连接的所有节点
+func (node *Node) Peers() []*Peer {
+	node.peerMutex.Lock()
+	defer node.peerMutex.Unlock()
+	var peers []*Peer
+	for peer := range node.peers {
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+func (node *Node) addPeer(peer *Peer) {
+	node.peerMutex.Lock()
+	defer node.peerMutex.Unlock()
+	node.peers[peer] = true
+}
+
+// 节点是否仍然连接
+func (node *Node) hasPeer(peer *Peer) bool {
+	node.peerMutex.Lock()
+	defer node.peerMutex.Unlock()
+	return node.peers[peer]
+}
+
+func (node *Node) removePeer(peer *Peer) {
+	node.peerMutex.Lock()
+	delete(node.peers, peer)
+	node.peerMutex.Unlock()
+	peer.Close()
+	node.syncManager.DonePeer(peer)
+	node.relayPeerDone(peer)
+}
+
+// 维持主动连接数量：定时从地址管理器中选择节点连接
+func (node *Node) connectionLoop() {
+	defer node.wg.Done()
+	ticker := time.NewTicker(connectInterval)
+	defer ticker.Stop()
+	for {
+		node.connectOutbound()
+		select {
+		case <-ticker.C:
+		case <-node.quit:
+			return
+		}
+	}
+}
+
+// 主动连接数量不足时连接新的节点
+func (node *Node) connectOutbound() {
+	exclude := map[string]bool{node.ListenAddr: true}
+	outbound := 0
+	for _, peer := range node.Peers() {
+		exclude[peer.Addr] = true
+		exclude[peer.ListenAddr()] = true
+		if !peer.Inbound {
+			outbound++
+		}
+	}
+	if outbound >= node.targetOutbound {
+		return
+	}
+	for _, ban := range node.banManager.List() {
+		exclude[ban.Addr] = true
+	}
+	for _, addr := range node.addrManager.Candidates(node.targetOutbound-outbound, exclude) {
+		select {
+		case <-node.quit:
+			return
+		default:
+		}
+		if node.banManager.IsBanned(addr) {
+			continue
+		}
+		if err := node.Connect(addr); err != nil {
+			netLog.Warnf("%v\n", err)
+		}
+	}
+}
+
+// 已完成握手的节点
+func (node *Node) HandshakedPeers() []*Peer {
+	var peers []*Peer
+	for _, peer := range node.Peers() {
+		if peer.handshaked() {
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
+
+// 最新区块的哈希与高度
+func (node *Node) BestBlock() ([]byte, int64) {
+	node.chainMutex.Lock()
+	defer node.chainMutex.Unlock()
+	return append([]byte{}, node.blockchain.Tip...), node.blockchain.Height()
+}
+
+// 在持有区块链锁的情况下访问区块链
+func (node *Node) withChain(fn func(blockchain *BlockChain)) {
+	node.chainMutex.Lock()
+	defer node.chainMutex.Unlock()
+	fn(node.blockchain)
+}
+
+// 当前区块高度
+func (node *Node) height() int64 {
+	node.chainMutex.Lock()
+	defer node.chainMutex.Unlock()
+	return node.blockchain.Height()
+}
+
+// 接受连接
+func (node *Node) acceptLoop() {
+	defer node.wg.Done()
+	for {
+		conn, err := node.listener.Accept()
+		if err != nil {
+			select {
+			case <-node.quit:
+				return
+			default:
+			}
+			netLog.Warnf("accept connection failed: %v\n", err)
+			continue
+		}
+		if node.banManager.IsBanned(conn.RemoteAddr().String()) {
+			conn.Close()
+			continue
+		}
+		peer := newPeer(conn, true)
+		node.addPeer(peer)
+		node.wg.Add(1)
+		go node.handlePeer(peer)
+	}
+}
+
+// 读取并处理对方发来的消息，直到连接断开
+func (node *Node) handlePeer(peer *Peer) {
+	defer node.wg.Done()
+	defer node.removePeer(peer)
+
+	node.wg.Add(1)
+	go node.pingLoop(peer)
+
+	for {
+		peer.conn.SetReadDeadline(time.Now().Add(peerTimeout))
+		msg, err := ReadMessage(peer.conn)
+		if err != nil {
+			select {
+			case <-peer.quit:
+			default:
+				netLog.Infof("节点 [%s] 断开连接：%v\n", peer, err)
+				if err == ErrMessageTooLarge {
+					node.misbehaving(peer, banScoreOversizedMessage, err.Error())
+				}
+			}
+			return
+		}
+		if peer.countMessage() && node.misbehaving(peer, banScoreMessageFlood, "message flood") {
+			return
+		}
+		if err := node.handleMessage(peer, msg); err != nil {
+			// 违规未达到封禁阈值时保持连接，其他错误断开连接
+			if m, ok := err.(*misbehaviorError); ok {
+				if node.misbehaving(peer, m.score, fmt.Sprintf("[%s] %v", msg.Command, m.err)) {
+					return
+				}
+				continue
+			}
+			netLog.Warnf("处理节点 [%s] 的 [%s] 消息失败：%v\n", peer, msg.Command, err)
+			return
+		}
+	}
+}
+
+// 定时发送ping，保持连接
+func (node *Node) pingLoop(peer *Peer) {
+	defer node.wg.Done()
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			var buf [8]byte
+			rand.Read(buf[:])
+			peer.SendMessage(cmdPing, buf[:])
+		case <-peer.quit:
+			return
+		}
+	}
+}
+
+// 分发消息
+func (node *Node) handleMessage(peer *Peer, msg *Message) error {
+	if msg.Command != cmdVersion && !peer.handshaked() {
+		return misbehavior(banScoreProtocol, fmt.Errorf("received [%s] before version", msg.Command))
+	}
+	switch msg.Command {
+	case cmdVersion:
+		return node.handleVersion(peer, msg.Payload)
+	case cmdVerack:
+		return nil
+	case cmdGetBlocks:
+		return node.handleGetBlocks(peer, msg.Payload)
+	case cmdGetHdrs:
+		return node.handleGetHeaders(peer, msg.Payload)
+	case cmdHeaders:
+		return node.handleHeaders(peer, msg.Payload)
+	case cmdInv:
+		return node.handleInv(peer, msg.Payload)
+	case cmdGetData:
+		return node.handleGetData(peer, msg.Payload)
+	case cmdNotFound:
+		return node.handleNotFound(peer, msg.Payload)
+	case cmdBlock:
+		return node.handleBlock(peer, msg.Payload)
+	case cmdTx:
+		return node.handleTx(peer, msg.Payload)
+	case cmdPing:
+		return peer.SendMessage(cmdPong, msg.Payload)
+	case cmdPong:
+		return nil
+	case cmdGetAddr:
+		return node.handleGetAddr(peer)
+	case cmdAddr:
+		return node.handleAddr(peer, msg.Payload)
+	}
+	// 忽略未知命令
+	netLog.Warnf("节点 [%s] 发送了未知命令 [%s]\n", peer, msg.Command)
+	return nil
+}
+
+// 发送version消息
+func (node *Node) sendVersion(peer *Peer) error {
+	msg := &VersionMsg{
+		Version:    protocolVersion,
+		BestHeight: node.height(),
+		AddrFrom:   node.ListenAddr,
+		Nonce:      node.nonce,
+	}
+	return peer.SendMessage(cmdVersion, msg.Serialize())
+}
+
+// 发送getblocks消息，请求对方发送本节点缺少的区块
+func (node *Node) sendGetBlocks(peer *Peer) error {
+	node.chainMutex.Lock()
+	locator, err := node.blockchain.BlockLocator()
+	node.chainMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return peer.SendMessage(cmdGetBlocks, serializeHashes(locator))
+}
+
+// 处理version消息
+func (node *Node) handleVersion(peer *Peer, payload []byte) error {
+	if peer.handshaked() {
+		return misbehavior(banScoreProtocol, errors.New("duplicate version message"))
+	}
+	msg, err := DeserializeVersionMsg(payload)
+	if err != nil {
+		return misbehavior(banScoreMalformedMessage, err)
+	}
+	if msg.Nonce == node.nonce {
+		return errors.New("connected to self")
+	}
+	// 对方发起的回环连接握手后才知道其封禁键
+	if banAddr := peerBanKey(peer.Addr, peer.Inbound, msg.AddrFrom); node.banManager.IsBanned(peer.Addr, banAddr) {
+		return fmt.Errorf("address [%s] is banned", banAddr)
+	}
+	for _, other := range node.Peers() {
+		if other != peer && other.handshaked() && other.Nonce() == msg.Nonce {
+			return errors.New("duplicate connection")
+		}
+	}
+	peer.stateMutex.Lock()
+	peer.versionReceived = true
+	peer.listenAddr = msg.AddrFrom
+	peer.nonce = msg.Nonce
+	peer.stateMutex.Unlock()
+	peer.updateHeight(msg.BestHeight)
+
+	// 对方发起的连接，需要回复本节点的version
+	if peer.Inbound {
+		if err := node.sendVersion(peer); err != nil {
+			return err
+		}
+	}
+	if err := peer.SendMessage(cmdVerack, nil); err != nil {
+		return err
+	}
+	netLog.Infof("与节点 [%s] 握手完成，对方高度 [%d]\n", peer, msg.BestHeight)
+
+	// 主动连接的节点可用，并向其请求更多地址；对方发起连接时记录其监听地址
+	if peer.Inbound {
+		node.addrManager.AddAddress(msg.AddrFrom, peer.Addr, time.Now())
+	} else {
+		node.addrManager.Good(peer.Addr)
+		if err := peer.SendMessage(cmdGetAddr, nil); err != nil {
+			return err
+		}
+	}
+
+	// 对方更高时开始同步
+	return node.syncManager.NewPeer(peer)
+}
+
+// 处理getaddr消息：返回已知的节点地址
+func (node *Node) handleGetAddr(peer *Peer) error {
+	var addrs []*NetAddress
+	for _, ka := range node.addrManager.Addresses() {
+		if len(addrs) >= maxAddrPerMsg {
+			break
+		}
+		addrs = append(addrs, &NetAddress{ka.Addr, ka.LastSeen})
+	}
+	if len(addrs) == 0 {
+		return nil
+	}
+	return peer.SendMessage(cmdAddr, serializeAddrs(addrs))
+}
+
+// 处理addr消息：记录对方发来的节点地址
+func (node *Node) handleAddr(peer *Peer, payload []byte) error {
+	addrs, err := deserializeAddrs(payload)
+	if err != nil {
+		return misbehavior(banScoreMalformedMessage, err)
+	}
+	for _, addr := range addrs {
+		if addr.Addr != node.ListenAddr {
+			node.addrManager.AddAddress(addr.Addr, peer.String(), time.Unix(addr.LastSeen, 0))
+		}
+	}
+	return nil
+}
+
+// 处理getblocks消息：返回对方缺少的区块哈希
+func (node *Node) handleGetBlocks(peer *Peer, payload []byte) error {
+	locator, err := deserializeHashes(payload)
+	if err != nil {
+		return misbehavior(banScoreMalformedMessage, err)
+	}
+	node.chainMutex.Lock()
+	hashes, err := node.blockchain.HashesAfterLocator(locator, maxBlocksPerInv)
+	node.chainMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	var invs []*InvVect
+	for _, hash := range hashes {
+		invs = append(invs, &InvVect{InvTypeBlock, hash})
+	}
+	return peer.SendMessage(cmdInv, serializeInv(invs))
+}
+
+// 处理getheaders消息：返回对方缺少的主链区块头
+func (node *Node) handleGetHeaders(peer *Peer, payload []byte) error {
+	locator, err := deserializeHashes(payload)
+	if err != nil {
+		return misbehavior(banScoreMalformedMessage, err)
+	}
+	node.chainMutex.Lock()
+	hashes, err := node.blockchain.HashesAfterLocator(locator, maxHeadersPerMsg)
+	node.chainMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	var headers []*BlockHeader
+	for _, hash := range hashes {
+		if block := node.blockchain.GetBlock(hash); block != nil {
+			headers = append(headers, block.Header())
+		}
+	}
+	return peer.SendMessage(cmdHeaders, serializeHeaders(headers))
+}
+
+// 处理headers消息
+func (node *Node) handleHeaders(peer *Peer, payload []byte) error {
+	headers, err := deserializeHeaders(payload)
+	if err != nil {
+		return misbehavior(banScoreMalformedMessage, err)
+	}
+	return node.syncManager.HandleHeaders(peer, headers)
+}
+
+// 处理inv消息：请求本节点缺少的区块和交易
+func (node *Node) handleInv(peer *Peer, payload []byte) error {
+	invs, err := deserializeInv(payload)
+	if err != nil {
+		return misbehavior(banScoreMalformedMessage, err)
+	}
+	var request []*InvVect
+	for _, inv := range invs {
+		peer.AddKnownInventory(inv)
+		switch inv.Type {
+		case InvTypeBlock:
+			// 同步期间区块由同步管理器下载
+			if node.syncManager.IsSyncing() {
+				continue
+			}
+			if !node.blockchain.HasBlock(inv.Hash) && node.requestInventory(peer, inv) {
+				request = append(request, inv)
+				peer.stateMutex.Lock()
+				peer.lastBlockHash = inv.Hash
+				peer.stateMutex.Unlock()
+			}
+		case InvTypeTx:
+			if !node.mempool.Has(inv.Hash) && node.requestInventory(peer, inv) {
+				request = append(request, inv)
+			}
+		}
+	}
+	if len(request) == 0 {
+		return nil
+	}
+	return peer.SendMessage(cmdGetData, serializeInv(request))
+}
+
+// 处理getdata消息：发送对方请求的区块和交易
+func (node *Node) handleGetData(peer *Peer, payload []byte) error {
+	invs, err := deserializeInv(payload)
+	if err != nil {
+		return misbehavior(banScoreMalformedMessage, err)
+	}
+	var notFound []*InvVect
+	for _, inv := range invs {
+		switch inv.Type {
+		case InvTypeBlock:
+			if block := node.blockchain.GetBlock(inv.Hash); block != nil {
+				if err := peer.SendMessage(cmdBlock, block.Serialize()); err != nil {
+					return err
+				}
+				peer.AddKnownInventory(inv)
+				continue
+			}
+		case InvTypeTx:
+			if tx := node.mempool.Get(inv.Hash); tx != nil {
+				if err := peer.SendMessage(cmdTx, tx.SerializeRaw()); err != nil {
+					return err
+				}
+				peer.AddKnownInventory(inv)
+				continue
+			}
+		}
+		notFound = append(notFound, inv)
+	}
+	// 告知对方本节点没有的对象，对方可以改向其他节点请求
+	if len(notFound) > 0 {
+		return peer.SendMessage(cmdNotFound, serializeInv(notFound))
+	}
+	return nil
+}
+
+// 处理notfound消息：对方没有请求的对象，改向其他节点请求
+func (node *Node) handleNotFound(peer *Peer, payload []byte) error {
+	invs, err := deserializeInv(payload)
+	if err != nil {
+		return misbehavior(banScoreMalformedMessage, err)
+	}
+	node.syncManager.HandleNotFound(peer, invs)
+	node.relayNotFound(peer, invs)
+	return nil
+}
+
+// 处理block消息
+func (node *Node) handleBlock(peer *Peer, payload []byte) error {
+	block, err := DecodeBlock(payload)
+	if err != nil {
+		return misbehavior(banScoreMalformedMessage, fmt.Errorf("decode block failed: %v", err))
+	}
+	peer.updateHeight(block.Height)
+	inv := &InvVect{InvTypeBlock, block.Hash}
+	peer.AddKnownInventory(inv)
+	node.inventoryReceived(inv)
+
+	// 同步过程中请求的区块
+	if node.syncManager.HandleBlock(peer, block) {
+		return nil
+	}
+
+	tipChanged, err := node.ProcessBlock(block)
+	if err == ErrOrphanBlock {
+		// 缺少父区块，向对方请求
+		return node.sendGetBlocks(peer)
+	}
+	if err != nil && err != ErrBlockExists {
+		node.misbehaving(peer, banScoreInvalidBlock, fmt.Sprintf("invalid block [%x]: %v", block.Hash, err))
+		return nil
+	}
+	if tipChanged {
+		netLog.Infof("接收区块 [%x]，高度 [%d]\n", block.Hash, block.Height)
+	}
+
+	// 上一批请求的区块已接收完毕，对方仍然更高时继续请求
+	peer.stateMutex.Lock()
+	lastBlock := bytes.Equal(peer.lastBlockHash, block.Hash)
+	peer.stateMutex.Unlock()
+	if lastBlock && peer.Height() > node.height() {
+		return node.sendGetBlocks(peer)
+	}
+	return nil
+}
+
+// 处理tx消息
+func (node *Node) handleTx(peer *Peer, payload []byte) error {
+	tx, err := DeserializeRawTransaction(payload)
+	if err != nil {
+		return misbehavior(banScoreMalformedMessage, fmt.Errorf("decode transaction failed: %v", err))
+	}
+	inv := &InvVect{InvTypeTx, tx.TxHash}
+	peer.AddKnownInventory(inv)
+	node.inventoryReceived(inv)
+	if err := node.AcceptTransaction(tx); err != nil {
+		netLog.Warnf("节点 [%s] 发送的交易 [%x] 未被接受：%v\n", peer, tx.TxHash, err)
+		return nil
+	}
+	netLog.Infof("接收交易 [%x]，交易池数量 [%d]\n", tx.TxHash, node.mempool.Count())
+	return nil
+}
+
+// 处理区块：验证并存入区块链，更新交易池，并通知其他节点
+func (node *Node) ProcessBlock(block *Block) (bool, error) {
+	var disconnected, connected []*Block
+	node.chainMutex.Lock()
+	oldTip := node.blockchain.Tip
+	tipChanged, err := node.blockchain.AcceptBlock(block)
+	if tipChanged {
+		disconnected, connected = node.blockchain.ChainChanges(oldTip, node.blockchain.Tip)
+	}
+	node.chainMutex.Unlock()
+	if err != nil {
+		return false, err
+	}
+	// 切换到其他分支时接入的区块可能不止一个
+	for _, connectedBlock := range connected {
+		node.removeBlockTxs(connectedBlock)
+	}
+	if len(disconnected) > 0 {
+		node.restoreDisconnectedTxs(disconnected)
+	}
+	node.broadcastInv(&InvVect{InvTypeBlock, block.Hash})
+	return tipChanged, nil
+}
+
+// 切换到其他分支后更新交易池：断开的区块中的交易(不含coinbase)按原顺序放回交易池之前，
+// 与交易池中的交易一起基于新的主链重新验证；已被新主链打包、与新主链冲突或引用的输出已不存在的交易被移除
+// disconnected按高度从高到低排列
+func (node *Node) restoreDisconnectedTxs(disconnected []*Block) {
+	var candidates []*Transaction
+	for index := len(disconnected) - 1; index >= 0; index-- {
+		for _, tx := range disconnected[index].Txs {
+			if !tx.IsCoinbaseTransaction() {
+				candidates = append(candidates, tx)
+			}
+		}
+	}
+	pending := node.mempool.Txs()
+	inMempool := make(map[string]bool)
+	for _, tx := range pending {
+		inMempool[string(tx.TxHash)] = true
+		node.mempool.Remove(tx.TxHash)
+	}
+	candidates = append(candidates, pending...)
+
+	var restored, removed []*Transaction
+	var accepted []*Transaction
+	node.chainMutex.Lock()
+	for _, tx := range candidates {
+		if node.mempool.Has(tx.TxHash) {
+			continue
+		}
+		if err := node.blockchain.VerifyTransaction(tx, accepted); err != nil {
+			if inMempool[string(tx.TxHash)] {
+				removed = append(removed, tx)
+			}
+			continue
+		}
+		node.mempool.Add(tx)
+		accepted = append(accepted, tx)
+		if !inMempool[string(tx.TxHash)] {
+			restored = append(restored, tx)
+		}
+	}
+	node.chainMutex.Unlock()
+
+	for _, tx := range removed {
+		node.blockchain.publish(&Event{Type: EventTxRemoved, Tx: tx})
+	}
+	for _, tx := range restored {
+		node.blockchain.publish(&Event{Type: EventTxAccepted, Tx: tx})
+		node.broadcastInv(&InvVect{InvTypeTx, tx.TxHash})
+	}
+	if len(restored) > 0 || len(removed) > 0 {
+		netLog.Infof("切换分支：断开 [%d] 个区块，[%d] 笔交易放回交易池，移除 [%d] 笔失效交易\n", len(disconnected), len(restored), len(removed))
+	}
+}
+
+// 从交易池中移除已被区块打包以及与之冲突的交易
+func (node *Node) removeBlockTxs(block *Block) {
+	for _, tx := range node.mempool.RemoveBlockTxs(block) {
+		node.blockchain.publish(&Event{Type: EventTxRemoved, Block: block, Tx: tx})
+	}
+}
+
+// 接收交易：验证通过后加入交易池，并通知其他节点
+func (node *Node) AcceptTransaction(tx *Transaction) error {
+	if node.mempool.Has(tx.TxHash) {
+		return errors.New("transaction already in mempool")
+	}
+	node.chainMutex.Lock()
+	if node.blockchain.IsEmpty() {
+		node.chainMutex.Unlock()
+		return errors.New("local chain is empty")
+	}
+	// 交易池中的交易作为缓存参与验证，避免与其冲突
+	err := node.blockchain.VerifyTransaction(tx, node.mempool.Txs())
+	node.chainMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if !node.mempool.Add(tx) {
+		return errors.New("transaction already in mempool")
+	}
+	node.blockchain.publish(&Event{Type: EventTxAccepted, Tx: tx})
+	node.broadcastInv(&InvVect{InvTypeTx, tx.TxHash})
+	return nil
+}
+
+// 从指定地址向目标地址转账：生成交易并加入交易池(可以花费交易池中尚未打包的输出)
+func (node *Node) SendToAddress(from, to string, amount Amount) (*Transaction, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("invalid amount [%s]", amount)
+	}
+	return node.SendMany(from, map[string]Amount{to: amount}, nil, nil)
+}
+
+// 从指定地址向多个地址转账：生成一笔交易并加入交易池
+// selector为nil时使用默认选币策略，data不为空时附加数据输出
+func (node *Node) SendMany(from string, outputs map[string]Amount, selector CoinSelector, data []byte) (*Transaction, error) {
+	if selector == nil {
+		selector, _ = NewCoinSelector(defaultCoinSelector)
+	}
+	node.chainMutex.Lock()
+	if node.blockchain.IsEmpty() {
+		node.chainMutex.Unlock()
+		return nil, errors.New("local chain is empty")
+	}
+	tx, err := NewMultiOutputTransaction(from, outputs, selector, node.blockchain, node.mempool.Txs())
+	node.chainMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := tx.AddDataOutput(data); err != nil {
+			return nil, fmt.Errorf("add data output failed: %v", err)
+		}
+	}
+	if err := node.AcceptTransaction(tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// 定时将交易池中的交易打包成区块
+func (node *Node) mineLoop() {
+	defer node.wg.Done()
+	ticker := time.NewTicker(mineInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			node.MineMempool()
+		case <-node.quit:
+			return
+		}
+	}
+}
+
+// 打包交易池中的交易，交易池为空时返回nil
+func (node *Node) MineMempool() *Block {
+	if node.mempool.Count() == 0 {
+		return nil
+	}
+	node.chainMutex.Lock()
+	if node.blockchain.IsEmpty() {
+		node.chainMutex.Unlock()
+		return nil
+	}
+	// 按接收顺序重新验证，丢弃已失效的交易
+	var txs []*Transaction
+	for _, tx := range node.mempool.Txs() {
+		if err := node.blockchain.VerifyTransaction(tx, txs); err != nil {
+			netLog.Warnf("交易 [%x] 已失效：%v\n", tx.TxHash, err)
+			node.mempool.Remove(tx.TxHash)
+			node.blockchain.publish(&Event{Type: EventTxRemoved, Tx: tx})
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	if len(txs) == 0 {
+		node.chainMutex.Unlock()
+		return nil
+	}
+	block := node.blockchain.MineBlock(txs)
+	node.chainMutex.Unlock()
+
+	node.removeBlockTxs(block)
+	netLog.Infof("挖出新区块 [%x]，高度 [%d]，交易数量 [%d]\n", block.Hash, block.Height, len(block.Txs))
+	node.broadcastInv(&InvVect{InvTypeBlock, block.Hash})
+	return block
+}
+
+// 将交易发送给指定节点
+// 先完成握手，发送交易后通过ping/pong确认对方已处理
+func SendTransactionToNode(addr string, tx *Transaction) error {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("connect to [%s] failed: %v", addr, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(peerTimeout))
+
+	var buf [8]byte
+	rand.Read(buf[:])
+	version := &VersionMsg{Version: protocolVersion, Nonce: binary.BigEndian.Uint64(buf[:])}
+	if err := WriteMessage(conn, cmdVersion, version.Serialize()); err != nil {
+		return err
+	}
+	if err := waitForMessage(conn, cmdVerack); err != nil {
+		return err
+	}
+	if err := WriteMessage(conn, cmdTx, tx.SerializeRaw()); err != nil {
+		return err
+	}
+	if err := WriteMessage(conn, cmdPing, buf[:]); err != nil {
+		return err
+	}
+	return waitForMessage(conn, cmdPong)
+}
+
+// 读取消息，直到收到指定命令
+func waitForMessage(conn net.Conn, command string) error {
+	for {
+		msg, err := ReadMessage(conn)
+		if err != nil {
+			return fmt.Errorf("wait for [%s] failed: %v", command, err)
+		}
+		if msg.Command == command {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package BLC
+
+import (
+	"encoding/hex"
+	"sync"
+)
+
+// 通知管理文件
+// 订阅区块链事件(见EventBus.go)，区块接入、断开主链，交易进入、移出交易池，
+// 以及关注地址的输出被创建、花费时通知订阅者
+// 订阅者的通知队列已满时(处理过慢)取消订阅并关闭通知通道，订阅者需重新订阅
+
+// 通知类型
+const (
+	NotifyBlockConnected    = "blockconnected"
+	NotifyBlockDisconnected = "blockdisconnected"
+	NotifyTxAccepted        = "txaccepted"
+	NotifyTxRemoved         = "txremoved"
+	NotifyAddressReceived   = "addressreceived" // 关注地址收到新的输出
+	NotifyAddressSpent      = "addressspent"    // 关注地址的输出被花费
+)
+
+// 每个订阅者的通知队列长度
+const subscriptionQueueSize = 256
+
+// 通知
+type Notification struct {
+	Type    string        `json:"type"`
+	Block   *BlockResult  `json:"block,omitempty"`
+	Tx      *TxResult     `json:"tx,omitempty"`
+	Address *AddressEvent `json:"address,omitempty"`
+}
+
+// 关注地址的输出被创建或花费
+type AddressEvent struct {
+	Address   string `json:"address"`
+	TxID      string `json:"txid"`                // 输出所属的交易
+	Vout      int    `json:"vout"`                // 输出索引
+	Amount    Amount `json:"amount,omitempty"`    // 输出金额，仅创建时提供
+	SpentBy   string `json:"spentby,omitempty"`   // 花费该输出的交易
+	BlockHash string `json:"blockhash,omitempty"` // 所在区块，交易池中的交易为空
+	Height    int64  `json:"height,omitempty"`
+}
+
+// 订阅
+type Subscription struct {
+	C         <-chan *Notification // 通知通道，取消订阅后关闭
+	c         chan *Notification
+	addresses map[string]bool // 关注的地址
+}
+
+// 通知管理器
+type Notifier struct {
+	mutex         sync.Mutex
+	subscriptions map[*Subscription]bool
+	events        *EventSubscription
+}
+
+// 创建通知管理器，订阅区块链事件
+func NewNotifier(blockchain *BlockChain) *Notifier {
+	notifier := &Notifier{subscriptions: make(map[*Subscription]bool)}
+	notifier.events = blockchain.Subscribe(notifier.handleEvent)
+	return notifier
+}
+
+// 将区块链事件转换为通知
+func (notifier *Notifier) handleEvent(event *Event) {
+	switch event.Type {
+	case EventBlockConnected:
+		notifier.blockConnected(event.Block)
+	case EventBlockDisconnected:
+		notifier.blockDisconnected(event.Block)
+	case EventTxAccepted:
+		notifier.txAccepted(event.Tx)
+	case EventTxRemoved:
+		notifier.txRemoved(event.Tx)
+	}
+}
+
+// 订阅通知，addresses为关注的地址
+func (notifier *Notifier) Subscribe(addresses []string) *Subscription {
+	c := make(chan *Notification, subscriptionQueueSize)
+	subscription := &Subscription{C: c, c: c, addresses: make(map[string]bool)}
+	for _, address := range addresses {
+		subscription.addresses[address] = true
+	}
+	notifier.mutex.Lock()
+	notifier.subscriptions[subscription] = true
+	notifier.mutex.Unlock()
+	return subscription
+}
+
+// 取消订阅
+func (notifier *Notifier) Unsubscribe(subscription *Subscription) {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+	notifier.remove(subscription)
+}
+
+// 调用时需持有notifier.mutex
+func (notifier *Notifier) remove(subscription *Subscription) {
+	if notifier.subscriptions[subscription] {
+		delete(notifier.subscriptions, subscription)
+		close(subscription.c)
+	}
+}
+
+// 取消区块链事件订阅及所有订阅
+func (notifier *Notifier) Close() {
+	notifier.events.Unsubscribe()
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+	for subscription := range notifier.subscriptions {
+		notifier.remove(subscription)
+	}
+}
+
+// 发送通知，address不为空时只发送给关注该地址的订阅者
+func (notifier *Notifier) send(notification *Notification, address string) {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+	for subscription := range notifier.subscriptions {
+		if address != "" && !subscription.addresses[address] {
+			continue
+		}
+		select {
+		case subscription.c <- notification:
+		default:
+			notifier.remove(subscription)
+		}
+	}
+}
+
+// 是否有订阅者关注指定地址
+func (notifier *Notifier) watched(address string) bool {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+	for subscription := range notifier.subscriptions {
+		if subscription.addresses[address] {
+			return true
+		}
+	}
+	return false
+}
+
+// 区块接入主链
+func (notifier *Notifier) blockConnected(block *Block) {
+	notifier.send(&Notification{Type: NotifyBlockConnected, Block: NewBlockResult(block, block.Height, false)}, "")
+	for _, tx := range block.Txs {
+		notifier.addressEvents(tx, block)
+	}
+}
+
+// 区块断开主链
+func (notifier *Notifier) blockDisconnected(block *Block) {
+	result := NewBlockResult(block, block.Height, false)
+	result.Confirmations = -1
+	notifier.send(&Notification{Type: NotifyBlockDisconnected, Block: result}, "")
+}
+
+// 交易进入交易池
+func (notifier *Notifier) txAccepted(tx *Transaction) {
+	notifier.send(&Notification{Type: NotifyTxAccepted, Tx: NewTxResult(tx, nil, 0)}, "")
+	notifier.addressEvents(tx, nil)
+}
+
+// 交易移出交易池
+func (notifier *Notifier) txRemoved(tx *Transaction) {
+	notifier.send(&Notification{Type: NotifyTxRemoved, Tx: NewTxResult(tx, nil, 0)}, "")
+}
+
+// 交易创建、花费关注地址的输出，block为nil表示交易尚在交易池中
+func (notifier *Notifier) addressEvents(tx *Transaction, block *Block) {
+	txid := hex.EncodeToString(tx.TxHash)
+	var blockHash string
+	var height int64
+	if block != nil {
+		blockHash, height = hex.EncodeToString(block.Hash), block.Height
+	}
+	if !tx.IsCoinbaseTransaction() {
+		for _, in := range tx.Vins {
+			if !notifier.watched(in.ScriptSig) {
+				continue
+			}
+			notifier.send(&Notification{Type: NotifyAddressSpent, Address: &AddressEvent{
+				Address:   in.ScriptSig,
+				TxID:      hex.EncodeToString(in.TxHash),
+				Vout:      in.Vout,
+				SpentBy:   txid,
+				BlockHash: blockHash,
+				Height:    height,
+			}}, in.ScriptSig)
+		}
+	}
+	for index, out := range tx.Vouts {
+		if out.IsDataOutput() || !notifier.watched(out.ScriptPubkey) {
+			continue
+		}
+		notifier.send(&Notification{Type: NotifyAddressReceived, Address: &AddressEvent{
+			Address:   out.ScriptPubkey,
+			TxID:      txid,
+			Vout:      index,
+			Amount:    out.Value,
+			BlockHash: blockHash,
+			Height:    height,
+		}}, out.ScriptPubkey)
+	}
+}
@@ -0,0 +1,164 @@
+package BLC
+
+import (
+	"context"
+	"encoding/hex"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 外部命令通知管理文件
+// 订阅区块链事件，执行startnode指定的命令，命令中的%s替换为区块哈希或交易哈希：
+//   -blocknotify：区块接入主链时执行
+//   -walletnotify：交易进入交易池、被区块打包时，若交易的输入或输出涉及-walletaddress指定的地址则执行
+// 命令由固定数量的工作goroutine执行，超时后终止；队列已满时丢弃，不影响区块处理
+
+const (
+	// 执行命令的工作goroutine数量
+	notifyWorkers = 4
+	// 等待执行的命令数量上限
+	notifyQueueSize = 100
+	// 单个命令的超时时间
+	notifyCommandTimeout = time.Minute
+)
+
+// 外部命令通知配置
+type NotifyConfig struct {
+	BlockNotify     string   // 区块接入主链时执行的命令
+	WalletNotify    string   // 涉及钱包地址的交易出现时执行的命令
+	WalletAddresses []string // 钱包地址
+}
+
+// 外部命令通知
+type NotifyHooks struct {
+	config       NotifyConfig
+	addresses    map[string]bool
+	commands     chan string
+	timeout      time.Duration // 单个命令的超时时间
+	subscription *EventSubscription
+	wg           sync.WaitGroup
+	ctx          context.Context // 停止时取消，终止正在执行的命令
+	cancel       context.CancelFunc
+}
+
+// 创建外部命令通知并订阅区块链事件
+func NewNotifyHooks(blockchain *BlockChain, config NotifyConfig) *NotifyHooks {
+	hooks := &NotifyHooks{
+		config:    config,
+		addresses: make(map[string]bool),
+		commands:  make(chan string, notifyQueueSize),
+		timeout:   notifyCommandTimeout,
+	}
+	hooks.ctx, hooks.cancel = context.WithCancel(context.Background())
+	for _, address := range config.WalletAddresses {
+		hooks.addresses[address] = true
+	}
+	for i := 0; i < notifyWorkers; i++ {
+		hooks.wg.Add(1)
+		go hooks.worker()
+	}
+	hooks.subscription = blockchain.Subscribe(hooks.handleEvent)
+	return hooks
+}
+
+// 停止执行命令，终止正在执行的命令，丢弃等待执行的命令
+func (hooks *NotifyHooks) Stop() {
+	hooks.subscription.Unsubscribe()
+	hooks.cancel()
+	hooks.wg.Wait()
+}
+
+func (hooks *NotifyHooks) handleEvent(event *Event) {
+	switch event.Type {
+	case EventBlockConnected:
+		if hooks.config.BlockNotify != "" {
+			hooks.enqueue(hooks.config.BlockNotify, event.Block.Hash)
+		}
+		if hooks.config.WalletNotify != "" {
+			for _, tx := range event.Block.Txs {
+				if hooks.isWalletTx(tx) {
+					hooks.enqueue(hooks.config.WalletNotify, tx.TxHash)
+				}
+			}
+		}
+	case EventTxAccepted:
+		if hooks.config.WalletNotify != "" && hooks.isWalletTx(event.Tx) {
+			hooks.enqueue(hooks.config.WalletNotify, event.Tx.TxHash)
+		}
+	}
+}
+
+// 交易的输入或输出是否涉及钱包地址
+func (hooks *NotifyHooks) isWalletTx(tx *Transaction) bool {
+	if !tx.IsCoinbaseTransaction() {
+		for _, in := range tx.Vins {
+			if hooks.addresses[in.ScriptSig] {
+				return true
+			}
+		}
+	}
+	for _, out := range tx.Vouts {
+		if !out.IsDataOutput() && hooks.addresses[out.ScriptPubkey] {
+			return true
+		}
+	}
+	return false
+}
+
+// 将%s替换为哈希后加入执行队列，队列已满时丢弃
+func (hooks *NotifyHooks) enqueue(command string, hash []byte) {
+	command = strings.ReplaceAll(command, "%s", hex.EncodeToString(hash))
+	select {
+	case hooks.commands <- command:
+	default:
+		hookLog.Warnf("通知命令队列已满，丢弃命令 [%s]\n", command)
+	}
+}
+
+func (hooks *NotifyHooks) worker() {
+	defer hooks.wg.Done()
+	for {
+		select {
+		case command := <-hooks.commands:
+			runNotifyCommand(hooks.ctx, command, hooks.timeout)
+		case <-hooks.ctx.Done():
+			return
+		}
+	}
+}
+
+// 通过系统shell执行命令，超过timeout后终止命令及其启动的子进程，命令的输出被丢弃
+func runNotifyCommand(parent context.Context, command string, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		hookLog.Warnf("通知命令 [%s] 启动失败：%v\n", command, err)
+		return
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			hookLog.Warnf("通知命令 [%s] 执行失败：%v\n", command, err)
+		}
+	case <-ctx.Done():
+		killProcessGroup(cmd)
+		<-done
+		if parent.Err() == nil {
+			hookLog.Warnf("通知命令 [%s] 超时被终止\n", command)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+//go:build !windows
+// +build !windows
+
+package BLC
+
+import (
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 等待文件内容满足条件
+func waitForFile(t *testing.T, path string, condition func(content string) bool) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := ioutil.ReadFile(path)
+		if condition(string(data)) {
+			return string(data)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s, content %q", path, data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBlockNotifyReplacesHash(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+	out := filepath.Join(t.TempDir(), "notify")
+	hooks := NewNotifyHooks(blockchain, NotifyConfig{BlockNotify: "echo %s-%s >> " + out})
+	defer hooks.Stop()
+
+	blockchain.AddBlock(nil)
+	hash := hex.EncodeToString(blockchain.Tip)
+	content := waitForFile(t, out, func(content string) bool { return content != "" })
+	if want := hash + "-" + hash + "\n"; content != want {
+		t.Fatalf("notify output = %q, want %q", content, want)
+	}
+}
+
+func TestWalletNotifyMatchesAddress(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+	out := filepath.Join(t.TempDir(), "notify")
+	hooks := NewNotifyHooks(blockchain, NotifyConfig{WalletNotify: "echo %s >> " + out, WalletAddresses: []string{"bob"}})
+	defer hooks.Stop()
+
+	other := &Transaction{Vins: []*TxInput{{[]byte{1}, 0, "alice"}}, Vouts: []*TxOutput{{Value: 1, ScriptPubkey: "carol"}}}
+	other.HashTransaction()
+	wallet := &Transaction{Vins: []*TxInput{{[]byte{2}, 0, "alice"}}, Vouts: []*TxOutput{{Value: 1, ScriptPubkey: "bob"}}}
+	wallet.HashTransaction()
+	blockchain.publish(&Event{Type: EventTxAccepted, Tx: other})
+	blockchain.publish(&Event{Type: EventTxAccepted, Tx: wallet})
+	waitForFile(t, out, func(content string) bool { return content != "" })
+	// 等待可能被错误执行的其他命令
+	time.Sleep(100 * time.Millisecond)
+	if content, _ := ioutil.ReadFile(out); string(content) != hex.EncodeToString(wallet.TxHash)+"\n" {
+		t.Fatalf("notify output = %q, want only the wallet transaction", content)
+	}
+}
+
+// 队列已满时丢弃命令，不阻塞区块链事件的分发
+func TestNotifyQueueFullDropsCommands(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+	hooks := NewNotifyHooks(blockchain, NotifyConfig{BlockNotify: "sleep 30"})
+	// 所有工作goroutine被占用后填满队列
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for len(hooks.commands) < cap(hooks.commands) {
+			hooks.enqueue("sleep 30", []byte{0})
+		}
+		time.Sleep(100 * time.Millisecond)
+		if len(hooks.commands) == cap(hooks.commands) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timeout filling the notify queue")
+		}
+	}
+
+	delivered := make(chan []byte, 1)
+	blockchain.Subscribe(func(event *Event) {
+		if event.Type == EventBlockConnected {
+			delivered <- event.Block.Hash
+		}
+	})
+	start := time.Now()
+	blockchain.AddBlock(nil)
+	select {
+	case hash := <-delivered:
+		if hex.EncodeToString(hash) != hex.EncodeToString(blockchain.Tip) {
+			t.Fatalf("delivered block %x, want %x", hash, blockchain.Tip)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event dispatch is blocked by the full notify queue")
+	}
+	if len(hooks.commands) != cap(hooks.commands) {
+		t.Fatalf("queue length = %d, want %d", len(hooks.commands), cap(hooks.commands))
+	}
+
+	// 停止时终止正在执行的命令
+	hooks.Stop()
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("AddBlock and Stop took %v", elapsed)
+	}
+}
+
+// 超时后终止命令所在的进程组，包括命令在后台启动的子进程
+func TestNotifyCommandTimeoutKillsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	start := time.Now()
+	runNotifyCommand(context.Background(), "sleep 30 & echo $! > "+pidFile+"; wait", 200*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("runNotifyCommand returned after %v", elapsed)
+	}
+	content := waitForFile(t, pidFile, func(content string) bool { return strings.HasSuffix(content, "\n") })
+	pid, err := strconv.Atoi(strings.TrimSpace(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("background process %d survived the timeout", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 进程是否仍在运行(僵尸进程视为已结束)
+func processAlive(pid int) bool {
+	data, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
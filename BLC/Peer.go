@@ -0,0 +1,176 @@
+package BLC
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// 对等节点管理文件
+
+// 每个节点记录的已知库存的最大数量，超过时淘汰最早的条目
+const maxKnownInventory = 5000
+
+// 消息频率限制：每个统计周期内允许的最大消息数
+const (
+	messageFloodWindow   = 10 * time.Second
+	maxMessagesPerWindow = 2000
+)
+
+// 对等节点
+type Peer struct {
+	conn    net.Conn
+	Addr    string // 连接地址
+	Inbound bool   // 是否为对方发起的连接
+
+	mutex           sync.Mutex // 保护连接写入
+	stateMutex      sync.Mutex // 保护下列状态
+	versionReceived bool
+	listenAddr      string // 对方在version消息中声明的监听地址
+	height          int64
+	nonce           uint64          // 对方在version消息中的随机数
+	lastBlockHash   []byte          // 最近一次请求的区块中最后一个区块的哈希
+	knownInventory  map[string]bool // 对方已拥有的区块与交易，避免重复发送
+	knownOrder      []string        // 已知库存的加入顺序
+	banScore        int             // 违规分数
+	messageCount    int             // 当前统计周期内收到的消息数
+	windowStart     time.Time       // 当前统计周期的开始时间
+
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+// 创建对等节点
+func newPeer(conn net.Conn, inbound bool) *Peer {
+	return &Peer{
+		conn:    conn,
+		Addr:    conn.RemoteAddr().String(),
+		Inbound: inbound,
+		quit:    make(chan struct{}),
+
+		knownInventory: make(map[string]bool),
+	}
+}
+
+// 发送消息
+func (peer *Peer) SendMessage(command string, payload []byte) error {
+	peer.mutex.Lock()
+	defer peer.mutex.Unlock()
+	peer.conn.SetWriteDeadline(time.Now().Add(peerTimeout))
+	return WriteMessage(peer.conn, command, payload)
+}
+
+// 关闭连接
+func (peer *Peer) Close() {
+	peer.closeOnce.Do(func() {
+		close(peer.quit)
+		peer.conn.Close()
+	})
+}
+
+// 对方的最新区块高度
+func (peer *Peer) Height() int64 {
+	peer.stateMutex.Lock()
+	defer peer.stateMutex.Unlock()
+	return peer.height
+}
+
+// 更新对方的最新区块高度(只增不减)
+func (peer *Peer) updateHeight(height int64) {
+	peer.stateMutex.Lock()
+	defer peer.stateMutex.Unlock()
+	if height > peer.height {
+		peer.height = height
+	}
+}
+
+// 是否已收到对方的version消息
+func (peer *Peer) handshaked() bool {
+	peer.stateMutex.Lock()
+	defer peer.stateMutex.Unlock()
+	return peer.versionReceived
+}
+
+// 对方在version消息中声明的监听地址
+func (peer *Peer) ListenAddr() string {
+	peer.stateMutex.Lock()
+	defer peer.stateMutex.Unlock()
+	return peer.listenAddr
+}
+
+// 对方在version消息中的随机数
+func (peer *Peer) Nonce() uint64 {
+	peer.stateMutex.Lock()
+	defer peer.stateMutex.Unlock()
+	return peer.nonce
+}
+
+// 累加违规分数，返回累加后的分数
+func (peer *Peer) addBanScore(score int) int {
+	peer.stateMutex.Lock()
+	defer peer.stateMutex.Unlock()
+	peer.banScore += score
+	return peer.banScore
+}
+
+// 违规分数
+func (peer *Peer) BanScore() int {
+	peer.stateMutex.Lock()
+	defer peer.stateMutex.Unlock()
+	return peer.banScore
+}
+
+// 统计收到的消息，当前统计周期内消息过多时返回true(每个周期只返回一次)
+func (peer *Peer) countMessage() bool {
+	peer.stateMutex.Lock()
+	defer peer.stateMutex.Unlock()
+	now := time.Now()
+	if now.Sub(peer.windowStart) > messageFloodWindow {
+		peer.windowStart = now
+		peer.messageCount = 0
+	}
+	peer.messageCount++
+	return peer.messageCount == maxMessagesPerWindow+1
+}
+
+// 封禁时使用的地址，由连接地址得到(对方发起的连接为conn.RemoteAddr()，本节点发起的连接为拨号地址)
+// 只有对方从回环地址发起的连接在握手后使用其声明的回环监听地址，见peerBanKey
+func (peer *Peer) banAddr() string {
+	return peerBanKey(peer.Addr, peer.Inbound, peer.ListenAddr())
+}
+
+// 对方的标识地址：优先使用监听地址
+func (peer *Peer) String() string {
+	if listenAddr := peer.ListenAddr(); listenAddr != "" {
+		return listenAddr
+	}
+	return peer.Addr
+}
+
+// 库存条目的键
+func inventoryKey(inv *InvVect) string {
+	return string([]byte{byte(inv.Type)}) + string(inv.Hash)
+}
+
+// 记录对方已拥有的库存(对方通知过、发送过或已发送给对方)
+func (peer *Peer) AddKnownInventory(inv *InvVect) {
+	peer.stateMutex.Lock()
+	defer peer.stateMutex.Unlock()
+	key := inventoryKey(inv)
+	if peer.knownInventory[key] {
+		return
+	}
+	if len(peer.knownOrder) >= maxKnownInventory {
+		delete(peer.knownInventory, peer.knownOrder[0])
+		peer.knownOrder = peer.knownOrder[1:]
+	}
+	peer.knownInventory[key] = true
+	peer.knownOrder = append(peer.knownOrder, key)
+}
+
+// 对方是否已拥有指定库存
+func (peer *Peer) KnowsInventory(inv *InvVect) bool {
+	peer.stateMutex.Lock()
+	defer peer.stateMutex.Unlock()
+	return peer.knownInventory[inventoryKey(inv)]
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package BLC
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// 在新的进程组中启动命令，以便终止命令时一并终止其启动的子进程
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// 终止命令所在的进程组
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,14 @@
+package BLC
+
+import (
+	"os/exec"
+)
+
+// windows下不单独创建进程组
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// 终止命令进程
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,103 @@
+package BLC
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// JSON-RPC客户端管理文件
+// 命令行通过-rpcconnect连接运行中的节点时使用，未指定用户名密码时读取数据目录中的cookie文件
+
+// 请求超时时间
+const rpcClientTimeout = 60 * time.Second
+
+// RPC客户端
+type RPCClient struct {
+	url      string
+	user     string
+	password string
+	client   *http.Client
+	nextID   uint64
+}
+
+// 创建RPC客户端，user为空时从cookie文件读取认证信息
+func NewRPCClient(addr, user, password string) (*RPCClient, error) {
+	if user == "" {
+		cookie, err := ioutil.ReadFile(CookieFile())
+		if err != nil {
+			return nil, fmt.Errorf("read cookie file failed (is the node running with -rpc?): %v", err)
+		}
+		parts := strings.SplitN(strings.TrimSpace(string(cookie)), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed cookie file [%s]", CookieFile())
+		}
+		user, password = parts[0], parts[1]
+	}
+	url := addr
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		if strings.HasPrefix(url, ":") {
+			url = "127.0.0.1" + url
+		}
+		url = "http://" + url + "/"
+	}
+	return &RPCClient{
+		url:      url,
+		user:     user,
+		password: password,
+		client:   &http.Client{Timeout: rpcClientTimeout},
+	}, nil
+}
+
+// 调用RPC方法，将结果解析到result中；服务端返回的错误为*RPCError
+func (client *RPCClient) Call(method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	id := atomic.AddUint64(&client.nextID, 1)
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, client.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.SetBasicAuth(client.user, client.password)
+	request.Header.Set("Content-Type", "application/json")
+	response, err := client.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("rpc authorization failed")
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("rpc request failed: %s", response.Status)
+	}
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&resp); err != nil {
+		return fmt.Errorf("decode rpc response failed: %v", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
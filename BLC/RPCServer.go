@@ -0,0 +1,608 @@
+package BLC
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// JSON-RPC服务管理文件
+// 通过HTTP POST提供JSON-RPC 2.0接口，支持批量请求，使用HTTP基本认证：
+//   指定-rpcuser/-rpcpassword时使用该用户名与密码；
+//   否则启动时生成随机密码写入数据目录中的cookie文件(用户名为__cookie__)，停止时删除
+
+// cookie认证的用户名
+const rpcCookieUser = "__cookie__"
+
+// cookie文件名，位于数据目录中
+const rpcCookieFileName = ".cookie"
+
+// 请求体的最大长度
+const maxRPCRequestSize = 1024 * 1024
+
+// JSON-RPC错误码
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	// 应用错误
+	rpcMiscError            = -1
+	rpcInvalidAddressOrID   = -5  // 区块或交易不存在
+	rpcInsufficientFunds    = -6  // 余额不足
+	rpcInvalidParameter     = -8  // 参数值无效
+	rpcDeserializationError = -22 // 原始交易无法解析
+	rpcVerifyRejected       = -26 // 交易未通过验证
+)
+
+// RPC配置
+type RPCConfig struct {
+	Addr     string // 监听地址，主机为空时只监听本机，例如:8332
+	User     string
+	Password string
+}
+
+// RPC错误
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+func newRPCError(code int, format string, args ...interface{}) *RPCError {
+	return &RPCError{code, fmt.Sprintf(format, args...)}
+}
+
+// JSON-RPC请求
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// JSON-RPC响应
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"` // 成功时总是存在(可能为null)
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// RPC方法：参数名用于支持按名称传参
+type rpcMethod struct {
+	params  []string
+	handler func(server *RPCServer, params []json.RawMessage) (interface{}, error)
+}
+
+// 所有RPC方法
+var rpcMethods map[string]*rpcMethod
+
+func init() {
+	rpcMethods = map[string]*rpcMethod{
+		"getblockcount":      {nil, rpcGetBlockCount},
+		"getblockhash":       {[]string{"height"}, rpcGetBlockHash},
+		"getblock":           {[]string{"blockhash", "verbose"}, rpcGetBlock},
+		"getbalance":         {[]string{"address", "height", "time"}, rpcGetBalance},
+		"listunspent":        {[]string{"address", "minconf", "maxconf"}, rpcListUnspent},
+		"gethistory":         {[]string{"address"}, rpcGetHistory},
+		"sendtoaddress":      {[]string{"from", "to", "amount"}, rpcSendToAddress},
+		"sendmany":           {[]string{"from", "amounts", "data", "coinselect"}, rpcSendMany},
+		"sendrawtransaction": {[]string{"hexstring"}, rpcSendRawTransaction},
+		"getrawmempool":      {nil, rpcGetRawMempool},
+		"gettransaction":     {[]string{"txid"}, rpcGetTransaction},
+		"listbanned":         {nil, rpcListBanned},
+		"setban":             {[]string{"address", "command", "bantime"}, rpcSetBan},
+		"clearbanned":        {nil, rpcClearBanned},
+	}
+}
+
+// RPC服务
+type RPCServer struct {
+	node       *Node
+	config     RPCConfig
+	cookieFile string
+	user       string
+	password   string
+	server     *http.Server
+}
+
+// 数据目录中cookie文件的路径
+func CookieFile() string {
+	return filepath.Join(filepath.Dir(dbName), rpcCookieFileName)
+}
+
+// 创建RPC服务，未指定用户名密码时认证信息写入数据目录中的cookie文件
+func NewRPCServer(node *Node, config RPCConfig) *RPCServer {
+	return &RPCServer{node: node, config: config, cookieFile: CookieFile()}
+}
+
+// 启动RPC服务
+func (server *RPCServer) Start() error {
+	if server.config.User != "" || server.config.Password != "" {
+		if server.config.User == "" || server.config.Password == "" {
+			return errors.New("both rpcuser and rpcpassword are required")
+		}
+		server.user, server.password = server.config.User, server.config.Password
+	} else {
+		var buf [32]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			return fmt.Errorf("generate cookie failed: %v", err)
+		}
+		server.user, server.password = rpcCookieUser, hex.EncodeToString(buf[:])
+		cookie := server.user + ":" + server.password
+		if err := ioutil.WriteFile(server.cookieFile, []byte(cookie), 0600); err != nil {
+			return fmt.Errorf("write cookie file [%s] failed: %v", server.cookieFile, err)
+		}
+	}
+
+	listener, err := listenLocal(server.config.Addr)
+	if err != nil {
+		server.removeCookie()
+		return err
+	}
+	server.server = &http.Server{Handler: server, ReadTimeout: 30 * time.Second}
+	go server.server.Serve(listener)
+	rpcLog.Infof("RPC服务已启动，监听地址 [%s]\n", listener.Addr())
+	return nil
+}
+
+// 监听HTTP服务地址，未指定主机时只监听本机
+func listenLocal(addr string) (net.Listener, error) {
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on [%s] failed: %v", addr, err)
+	}
+	return listener, nil
+}
+
+// 停止RPC服务
+func (server *RPCServer) Stop() {
+	if server.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.server.Shutdown(ctx)
+	}
+	server.removeCookie()
+}
+
+func (server *RPCServer) removeCookie() {
+	if server.config.User == "" {
+		os.Remove(server.cookieFile)
+	}
+}
+
+// 校验HTTP基本认证
+func (server *RPCServer) authorized(r *http.Request) bool {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(server.user)) == 1
+	passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(server.password)) == 1
+	return userOK && passwordOK
+}
+
+// 处理HTTP请求
+func (server *RPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "JSON-RPC server handles only POST requests", http.StatusMethodNotAllowed)
+		return
+	}
+	if !server.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="jsonrpc"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRPCRequestSize))
+	if err != nil {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var response interface{}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		// 批量请求
+		var requests []json.RawMessage
+		if err := json.Unmarshal(body, &requests); err != nil || len(requests) == 0 {
+			response = errorResponse(nil, newRPCError(rpcInvalidRequest, "invalid batch request"))
+		} else {
+			var responses []*rpcResponse
+			for _, raw := range requests {
+				if resp := server.handleRequest(raw); resp != nil {
+					responses = append(responses, resp)
+				}
+			}
+			if len(responses) == 0 {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			response = responses
+		}
+	} else {
+		resp := server.handleRequest(body)
+		if resp == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		response = resp
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func errorResponse(id json.RawMessage, rpcErr *RPCError) *rpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &rpcResponse{JSONRPC: "2.0", Error: rpcErr, ID: id}
+}
+
+// 处理单个请求，通知(没有id)返回nil
+func (server *RPCServer) handleRequest(raw json.RawMessage) *rpcResponse {
+	var request rpcRequest
+	if err := json.Unmarshal(raw, &request); err != nil {
+		return errorResponse(nil, newRPCError(rpcParseError, "parse error: %v", err))
+	}
+	if request.JSONRPC != "2.0" || request.Method == "" {
+		return errorResponse(request.ID, newRPCError(rpcInvalidRequest, "invalid request"))
+	}
+	result, err := server.Call(request.Method, request.Params)
+	if request.ID == nil {
+		return nil
+	}
+	if err != nil {
+		rpcErr, ok := err.(*RPCError)
+		if !ok {
+			rpcErr = newRPCError(rpcMiscError, "%v", err)
+		}
+		return errorResponse(request.ID, rpcErr)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(request.ID, newRPCError(rpcInternalError, "encode result failed: %v", err))
+	}
+	return &rpcResponse{JSONRPC: "2.0", Result: data, ID: request.ID}
+}
+
+// 调用RPC方法，参数为JSON数组(按位置)或JSON对象(按名称)
+func (server *RPCServer) Call(method string, rawParams json.RawMessage) (interface{}, error) {
+	m, ok := rpcMethods[method]
+	if !ok {
+		return nil, newRPCError(rpcMethodNotFound, "method [%s] not found", method)
+	}
+	var params []json.RawMessage
+	rawParams = bytes.TrimSpace(rawParams)
+	switch {
+	case len(rawParams) == 0 || string(rawParams) == "null":
+	case rawParams[0] == '[':
+		if err := json.Unmarshal(rawParams, &params); err != nil {
+			return nil, newRPCError(rpcInvalidParams, "invalid params: %v", err)
+		}
+	case rawParams[0] == '{':
+		var named map[string]json.RawMessage
+		if err := json.Unmarshal(rawParams, &named); err != nil {
+			return nil, newRPCError(rpcInvalidParams, "invalid params: %v", err)
+		}
+		for index, name := range m.params {
+			if value, ok := named[name]; ok {
+				for len(params) < index {
+					params = append(params, json.RawMessage("null"))
+				}
+				params = append(params, value)
+				delete(named, name)
+			}
+		}
+		for name := range named {
+			return nil, newRPCError(rpcInvalidParams, "unknown param [%s]", name)
+		}
+	default:
+		return nil, newRPCError(rpcInvalidParams, "params must be an array or an object")
+	}
+	if len(params) > len(m.params) {
+		return nil, newRPCError(rpcInvalidParams, "too many params, expected at most %d", len(m.params))
+	}
+	return m.handler(server, params)
+}
+
+// 解析参数：前required个参数必须提供，未提供或为null的可选参数保持原值
+func parseParams(params []json.RawMessage, required int, targets ...interface{}) error {
+	for index, target := range targets {
+		if index >= len(params) || string(params[index]) == "null" {
+			if index < required {
+				return newRPCError(rpcInvalidParams, "missing param #%d", index+1)
+			}
+			continue
+		}
+		if err := json.Unmarshal(params[index], target); err != nil {
+			return newRPCError(rpcInvalidParams, "invalid param #%d: %v", index+1, err)
+		}
+	}
+	return nil
+}
+
+// 解析hex格式的哈希
+func parseHash(str string) ([]byte, error) {
+	hash, err := hex.DecodeString(str)
+	if err != nil || len(hash) != 32 {
+		return nil, newRPCError(rpcInvalidParameter, "invalid hash [%s]", str)
+	}
+	return hash, nil
+}
+
+func rpcGetBlockCount(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	_, height := server.node.BestBlock()
+	return height, nil
+}
+
+func rpcGetBlockHash(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	var height int64
+	if err := parseParams(params, 1, &height); err != nil {
+		return nil, err
+	}
+	var block *Block
+	server.node.withChain(func(blockchain *BlockChain) {
+		block = blockchain.GetBlockByHeight(height)
+	})
+	if block == nil {
+		return nil, newRPCError(rpcInvalidParameter, "block height [%d] out of range", height)
+	}
+	return hex.EncodeToString(block.Hash), nil
+}
+
+func rpcGetBlock(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	var hashStr string
+	verbose := false
+	if err := parseParams(params, 1, &hashStr, &verbose); err != nil {
+		return nil, err
+	}
+	hash, err := parseHash(hashStr)
+	if err != nil {
+		return nil, err
+	}
+	var result *BlockResult
+	server.node.withChain(func(blockchain *BlockChain) {
+		if block := blockchain.GetBlock(hash); block != nil {
+			result = NewBlockResult(block, blockchain.Height(), verbose)
+			if !blockchain.IsMainChain(block) {
+				result.Confirmations = -1
+			}
+		}
+	})
+	if result == nil {
+		return nil, newRPCError(rpcInvalidAddressOrID, "block [%s] not found", hashStr)
+	}
+	return result, nil
+}
+
+func rpcGetBalance(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	var address string
+	var height, t int64
+	if err := parseParams(params, 1, &address, &height, &t); err != nil {
+		return nil, err
+	}
+	if height < 0 || t < 0 {
+		return nil, newRPCError(rpcInvalidParameter, "height [%d] and time [%d] must not be negative", height, t)
+	}
+	var balance Amount
+	var err error
+	server.node.withChain(func(blockchain *BlockChain) {
+		if !blockchain.IsEmpty() {
+			balance, err = blockchain.BalanceAt(address, height, t)
+		}
+	})
+	if err != nil {
+		return nil, newRPCError(rpcInvalidParameter, "%v", err)
+	}
+	return balance, nil
+}
+
+func rpcListUnspent(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	var address string
+	minConf, maxConf := int64(defaultMinConf), int64(defaultMaxConf)
+	if err := parseParams(params, 1, &address, &minConf, &maxConf); err != nil {
+		return nil, err
+	}
+	if minConf < 0 || maxConf < minConf {
+		return nil, newRPCError(rpcInvalidParameter, "invalid confirmations range [%d, %d]", minConf, maxConf)
+	}
+	results := []*UTXOResult{}
+	server.node.withChain(func(blockchain *BlockChain) {
+		if blockchain.IsEmpty() {
+			return
+		}
+		tipHeight := blockchain.Height()
+		for _, utxo := range blockchain.ListUnspent(address, minConf, maxConf) {
+			results = append(results, NewUTXOResult(utxo, tipHeight))
+		}
+	})
+	return results, nil
+}
+
+func rpcGetHistory(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	var address string
+	if err := parseParams(params, 1, &address); err != nil {
+		return nil, err
+	}
+	var entries []*AddressIndexEntry
+	var err error
+	server.node.withChain(func(blockchain *BlockChain) {
+		entries, err = blockchain.AddressIndex(address)
+	})
+	if err != nil {
+		return nil, newRPCError(rpcInternalError, "%v", err)
+	}
+	return NewHistoryResults(entries), nil
+}
+
+func rpcSendToAddress(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	var from, to string
+	var amount Amount
+	if err := parseParams(params, 3, &from, &to, &amount); err != nil {
+		return nil, err
+	}
+	if from == "" || to == "" {
+		return nil, newRPCError(rpcInvalidParameter, "from and to address are required")
+	}
+	if amount <= 0 || !amount.IsValid() {
+		return nil, newRPCError(rpcInvalidParameter, "invalid amount [%s]", amount)
+	}
+	tx, err := server.node.SendToAddress(from, to, amount)
+	if err != nil {
+		return nil, sendError(err)
+	}
+	return hex.EncodeToString(tx.TxHash), nil
+}
+
+// amounts为目标地址到金额的对象；data为附加数据(hex)；coinselect为选币策略名称
+func rpcSendMany(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	var from, dataHex, coinSelect string
+	var amounts map[string]Amount
+	if err := parseParams(params, 2, &from, &amounts, &dataHex, &coinSelect); err != nil {
+		return nil, err
+	}
+	if from == "" || len(amounts) == 0 {
+		return nil, newRPCError(rpcInvalidParameter, "from address and amounts are required")
+	}
+	for to, amount := range amounts {
+		if to == "" || amount <= 0 || !amount.IsValid() {
+			return nil, newRPCError(rpcInvalidParameter, "invalid amount [%s] to [%s]", amount, to)
+		}
+	}
+	data, err := hex.DecodeString(dataHex)
+	if err != nil || len(data) > MaxDataOutputSize {
+		return nil, newRPCError(rpcInvalidParameter, "invalid data, expected hex of at most %d bytes", MaxDataOutputSize)
+	}
+	selector, err := NewCoinSelector(coinSelect)
+	if err != nil {
+		return nil, newRPCError(rpcInvalidParameter, "%v", err)
+	}
+	tx, err := server.node.SendMany(from, amounts, selector, data)
+	if err != nil {
+		return nil, sendError(err)
+	}
+	return hex.EncodeToString(tx.TxHash), nil
+}
+
+// 转账失败的RPC错误
+func sendError(err error) *RPCError {
+	if strings.HasPrefix(err.Error(), "insufficient funds") {
+		return newRPCError(rpcInsufficientFunds, "%v", err)
+	}
+	return newRPCError(rpcVerifyRejected, "%v", err)
+}
+
+// 验证已签名的原始交易并加入交易池
+func rpcSendRawTransaction(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	var rawHex string
+	if err := parseParams(params, 1, &rawHex); err != nil {
+		return nil, err
+	}
+	tx, err := DecodeRawTransaction(rawHex)
+	if err != nil {
+		return nil, newRPCError(rpcDeserializationError, "%v", err)
+	}
+	if err := server.node.AcceptTransaction(tx); err != nil {
+		return nil, newRPCError(rpcVerifyRejected, "%v", err)
+	}
+	return hex.EncodeToString(tx.TxHash), nil
+}
+
+func rpcGetRawMempool(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	txids := []string{}
+	for _, tx := range server.node.mempool.Txs() {
+		txids = append(txids, hex.EncodeToString(tx.TxHash))
+	}
+	return txids, nil
+}
+
+func rpcGetTransaction(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	var txid string
+	if err := parseParams(params, 1, &txid); err != nil {
+		return nil, err
+	}
+	txHash, err := parseHash(txid)
+	if err != nil {
+		return nil, err
+	}
+	if tx := server.node.mempool.Get(txHash); tx != nil {
+		return NewTxResult(tx, nil, 0), nil
+	}
+	var result *TxResult
+	server.node.withChain(func(blockchain *BlockChain) {
+		if blockchain.IsEmpty() {
+			return
+		}
+		if tx, block := blockchain.FindTransaction(txHash); tx != nil {
+			result = NewTxResult(tx, block, blockchain.Height())
+		}
+	})
+	if result == nil {
+		return nil, newRPCError(rpcInvalidAddressOrID, "transaction [%s] not found", txid)
+	}
+	return result, nil
+}
+
+func rpcListBanned(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	return NewBanResults(server.node.banManager.List()), nil
+}
+
+// command为add时封禁并断开已有的连接，为remove时解除封禁
+func rpcSetBan(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	var address, command string
+	bantime := int64(defaultBanDuration / time.Second)
+	if err := parseParams(params, 2, &address, &command, &bantime); err != nil {
+		return nil, err
+	}
+	if address == "" {
+		return nil, newRPCError(rpcInvalidParameter, "address is required")
+	}
+	switch command {
+	case "add":
+		if bantime <= 0 {
+			return nil, newRPCError(rpcInvalidParameter, "invalid bantime [%d]", bantime)
+		}
+		duration := time.Duration(bantime) * time.Second
+		if err := server.node.Ban(address, duration, "manually banned"); err != nil {
+			return nil, newRPCError(rpcMiscError, "%v", err)
+		}
+		return &BanStatusResult{Address: address, Banned: true, Until: time.Now().Add(duration).Unix()}, nil
+	case "remove":
+		removed, err := server.node.banManager.Unban(address)
+		if err != nil {
+			return nil, newRPCError(rpcMiscError, "%v", err)
+		}
+		if !removed {
+			return nil, newRPCError(rpcInvalidParameter, "address [%s] is not banned", address)
+		}
+		return &BanStatusResult{Address: address}, nil
+	}
+	return nil, newRPCError(rpcInvalidParameter, "invalid command [%s], expected add or remove", command)
+}
+
+func rpcClearBanned(server *RPCServer, params []json.RawMessage) (interface{}, error) {
+	if err := server.node.banManager.Clear(); err != nil {
+		return nil, newRPCError(rpcMiscError, "%v", err)
+	}
+	return nil, nil
+}
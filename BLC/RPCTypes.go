@@ -0,0 +1,250 @@
+package BLC
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// RPC结果结构文件
+// 区块、交易、UTXO的JSON表示，哈希均为hex字符串，金额为十进制数字
+
+// 交易输入
+type TxInputResult struct {
+	TxID      string `json:"txid,omitempty"` // 引用的交易哈希，coinbase交易为空
+	Vout      int    `json:"vout"`
+	ScriptSig string `json:"scriptsig"`
+	Coinbase  bool   `json:"coinbase,omitempty"`
+}
+
+// 交易输出
+type TxOutputResult struct {
+	N       int    `json:"n"`
+	Value   Amount `json:"value"`
+	Address string `json:"address,omitempty"`
+	Data    string `json:"data,omitempty"` // 数据输出的附加数据(hex)
+}
+
+// 交易
+type TxResult struct {
+	TxID          string            `json:"txid"`
+	Vin           []*TxInputResult  `json:"vin"`
+	Vout          []*TxOutputResult `json:"vout"`
+	BlockHash     string            `json:"blockhash,omitempty"`
+	Height        int64             `json:"height,omitempty"`
+	Confirmations int64             `json:"confirmations"` // 0表示尚在交易池中
+	Time          int64             `json:"time,omitempty"`
+}
+
+// 区块
+type BlockResult struct {
+	Hash              string      `json:"hash"`
+	PreviousBlockHash string      `json:"previousblockhash,omitempty"`
+	Height            int64       `json:"height"`
+	Time              int64       `json:"time"`
+	Nonce             int64       `json:"nonce"`
+	Confirmations     int64       `json:"confirmations"`
+	TxIDs             []string    `json:"tx,omitempty"`
+	Txs               []*TxResult `json:"txs,omitempty"` // 详细模式下的交易
+}
+
+// 未花费输出
+type UTXOResult struct {
+	TxID          string `json:"txid"`
+	Vout          int    `json:"vout"`
+	Address       string `json:"address"`
+	Amount        Amount `json:"amount"`
+	Height        int64  `json:"height"`
+	Confirmations int64  `json:"confirmations"`
+}
+
+// 地址相关的交易
+type AddressTxResult struct {
+	TxID      string `json:"txid"`
+	BlockHash string `json:"blockhash"`
+	Height    int64  `json:"height"`
+	Time      int64  `json:"time"`
+	Received  Amount `json:"received"`
+	Sent      Amount `json:"sent"`
+}
+
+// 地址交易历史中的一项，balance为该交易之后的余额
+type HistoryResult struct {
+	*AddressTxResult
+	Delta   Amount `json:"delta"`
+	Balance Amount `json:"balance"`
+}
+
+// 地址余额与交易历史
+type AddressResult struct {
+	Address string             `json:"address"`
+	Balance Amount             `json:"balance"`
+	Txs     []*AddressTxResult `json:"txs"`
+}
+
+// 交易的JSON表示，block为nil表示交易尚在交易池中
+func NewTxResult(tx *Transaction, block *Block, tipHeight int64) *TxResult {
+	result := &TxResult{TxID: hex.EncodeToString(tx.TxHash)}
+	for _, in := range tx.Vins {
+		input := &TxInputResult{Vout: in.Vout, ScriptSig: in.ScriptSig}
+		if tx.IsCoinbaseTransaction() {
+			input.Coinbase = true
+		} else {
+			input.TxID = hex.EncodeToString(in.TxHash)
+		}
+		result.Vin = append(result.Vin, input)
+	}
+	for index, out := range tx.Vouts {
+		output := &TxOutputResult{N: index, Value: out.Value, Address: out.ScriptPubkey}
+		if out.IsDataOutput() {
+			output.Data = hex.EncodeToString(out.Data)
+		}
+		result.Vout = append(result.Vout, output)
+	}
+	if block != nil {
+		result.BlockHash = hex.EncodeToString(block.Hash)
+		result.Height = block.Height
+		result.Confirmations = tipHeight - block.Height + 1
+		result.Time = block.TimeStamp
+	}
+	return result
+}
+
+// 区块的JSON表示，verbose为true时包含完整交易，否则只包含交易哈希
+func NewBlockResult(block *Block, tipHeight int64, verbose bool) *BlockResult {
+	result := &BlockResult{
+		Hash:          hex.EncodeToString(block.Hash),
+		Height:        block.Height,
+		Time:          block.TimeStamp,
+		Nonce:         block.Nonce,
+		Confirmations: tipHeight - block.Height + 1,
+	}
+	if len(block.PrevBlockHash) > 0 {
+		result.PreviousBlockHash = hex.EncodeToString(block.PrevBlockHash)
+	}
+	for _, tx := range block.Txs {
+		if verbose {
+			result.Txs = append(result.Txs, NewTxResult(tx, block, tipHeight))
+		} else {
+			result.TxIDs = append(result.TxIDs, hex.EncodeToString(tx.TxHash))
+		}
+	}
+	return result
+}
+
+// UTXO的JSON表示
+func NewUTXOResult(utxo *UTXO, tipHeight int64) *UTXOResult {
+	result := &UTXOResult{
+		TxID:    hex.EncodeToString(utxo.TxHash),
+		Vout:    utxo.Index,
+		Address: utxo.Output.ScriptPubkey,
+		Amount:  utxo.Output.Value,
+		Height:  utxo.Height,
+	}
+	if utxo.Height > 0 {
+		result.Confirmations = tipHeight - utxo.Height + 1
+	}
+	return result
+}
+
+// 地址交易历史的JSON表示，交易按高度从高到低排列
+func NewAddressResult(address string, balance Amount, history []*AddressTx) *AddressResult {
+	result := &AddressResult{Address: address, Balance: balance, Txs: []*AddressTxResult{}}
+	for index := len(history) - 1; index >= 0; index-- {
+		entry := history[index]
+		result.Txs = append(result.Txs, &AddressTxResult{
+			TxID:      hex.EncodeToString(entry.Tx.TxHash),
+			BlockHash: hex.EncodeToString(entry.Block.Hash),
+			Height:    entry.Block.Height,
+			Time:      entry.Block.TimeStamp,
+			Received:  entry.Received,
+			Sent:      entry.Sent,
+		})
+	}
+	return result
+}
+
+// 地址交易历史的JSON表示，按交易在主链上的顺序排列并计算每笔交易之后的余额
+func NewHistoryResults(entries []*AddressIndexEntry) []*HistoryResult {
+	results := []*HistoryResult{}
+	var balance Amount
+	for _, entry := range entries {
+		balance += entry.Delta()
+		results = append(results, &HistoryResult{
+			AddressTxResult: &AddressTxResult{
+				TxID:      hex.EncodeToString(entry.TxHash),
+				BlockHash: hex.EncodeToString(entry.BlockHash),
+				Height:    entry.Height,
+				Time:      entry.TimeStamp,
+				Received:  entry.Received,
+				Sent:      entry.Sent,
+			},
+			Delta:   entry.Delta(),
+			Balance: balance,
+		})
+	}
+	return results
+}
+
+// 由JSON表示还原交易，用于命令行客户端按本地格式输出
+func (result *TxResult) Transaction() (*Transaction, error) {
+	txHash, err := hex.DecodeString(result.TxID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid txid [%s]: %v", result.TxID, err)
+	}
+	tx := &Transaction{TxHash: txHash}
+	for _, input := range result.Vin {
+		hash, err := hex.DecodeString(input.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input txid [%s]: %v", input.TxID, err)
+		}
+		tx.Vins = append(tx.Vins, &TxInput{TxHash: hash, Vout: input.Vout, ScriptSig: input.ScriptSig})
+	}
+	for _, output := range result.Vout {
+		data, err := hex.DecodeString(output.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output data [%s]: %v", output.Data, err)
+		}
+		if len(data) == 0 {
+			data = nil
+		}
+		tx.Vouts = append(tx.Vouts, &TxOutput{Value: output.Value, ScriptPubkey: output.Address, Data: data})
+	}
+	return tx, nil
+}
+
+// 由详细模式的JSON表示还原区块
+func (result *BlockResult) Block() (*Block, error) {
+	hash, err := hex.DecodeString(result.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block hash [%s]: %v", result.Hash, err)
+	}
+	prevHash, err := hex.DecodeString(result.PreviousBlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid previous block hash [%s]: %v", result.PreviousBlockHash, err)
+	}
+	block := &Block{
+		TimeStamp:     result.Time,
+		Hash:          hash,
+		PrevBlockHash: prevHash,
+		Height:        result.Height,
+		Nonce:         result.Nonce,
+	}
+	for _, txResult := range result.Txs {
+		tx, err := txResult.Transaction()
+		if err != nil {
+			return nil, err
+		}
+		block.Txs = append(block.Txs, tx)
+	}
+	return block, nil
+}
+
+// 将UTXO的JSON表示还原为UTXO
+func (result *UTXOResult) UTXO() (*UTXO, error) {
+	txHash, err := hex.DecodeString(result.TxID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid txid [%s]: %v", result.TxID, err)
+	}
+	output := &TxOutput{Value: result.Amount, ScriptPubkey: result.Address}
+	return &UTXO{TxHash: txHash, Index: result.Vout, Output: output, Height: result.Height}, nil
+}
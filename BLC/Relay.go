@@ -0,0 +1,145 @@
+package BLC
+
+import (
+	"time"
+)
+
+// 区块与交易转发管理文件
+// 新交易进入交易池、新区块接入区块链后通过inv通知所有节点，对方缺少时通过getdata请求；
+// 每个对象只向一个节点请求，请求超时或节点断开后改向其他通知过该对象的节点请求，
+// 没有其他节点时超时的请求向原节点重试
+
+const (
+	// 区块或交易请求超时时间
+	inventoryRequestTimeout = 30 * time.Second
+	// 检查请求超时的间隔
+	relayTickInterval = 5 * time.Second
+	// 同一对象最多请求的次数
+	maxInventoryAttempts = 3
+)
+
+// 正在请求的区块或交易
+type inventoryRequest struct {
+	inv        *InvVect
+	peer       *Peer     // 正在向其请求的节点
+	time       time.Time // 请求时间
+	announcers []*Peer   // 同样通知过该对象的其他节点
+	attempts   int       // 已请求的次数
+}
+
+// 登记对方通知的库存，返回是否需要向对方请求
+// 同一对象已在向其他节点请求时只记录通知方，不重复请求
+func (node *Node) requestInventory(peer *Peer, inv *InvVect) bool {
+	node.relayMutex.Lock()
+	defer node.relayMutex.Unlock()
+	key := inventoryKey(inv)
+	if request, ok := node.inFlight[key]; ok {
+		if request.peer == peer {
+			return false
+		}
+		for _, announcer := range request.announcers {
+			if announcer == peer {
+				return false
+			}
+		}
+		request.announcers = append(request.announcers, peer)
+		return false
+	}
+	node.inFlight[key] = &inventoryRequest{inv: inv, peer: peer, time: time.Now(), attempts: 1}
+	return true
+}
+
+// 已收到请求的对象
+func (node *Node) inventoryReceived(inv *InvVect) {
+	node.relayMutex.Lock()
+	defer node.relayMutex.Unlock()
+	delete(node.inFlight, inventoryKey(inv))
+}
+
+// 节点断开连接：改向其他节点请求其未完成的对象
+func (node *Node) relayPeerDone(peer *Peer) {
+	node.retryInventory(func(request *inventoryRequest) bool {
+		return request.peer == peer
+	}, false)
+}
+
+// 对方没有请求的对象：改向其他通知过该对象的节点请求
+func (node *Node) relayNotFound(peer *Peer, invs []*InvVect) {
+	keys := make(map[string]bool)
+	for _, inv := range invs {
+		keys[inventoryKey(inv)] = true
+	}
+	node.retryInventory(func(request *inventoryRequest) bool {
+		return request.peer == peer && keys[inventoryKey(request.inv)]
+	}, false)
+}
+
+// 重新请求满足条件的对象：改由下一个仍然连接的通知方提供，
+// 没有通知方时，retrySame为true则向原节点重试(不超过最大请求次数)，否则放弃
+func (node *Node) retryInventory(retry func(request *inventoryRequest) bool, retrySame bool) {
+	type getData struct {
+		peer *Peer
+		inv  *InvVect
+	}
+	var pending []getData
+
+	node.relayMutex.Lock()
+	for key, request := range node.inFlight {
+		if !retry(request) {
+			continue
+		}
+		var next *Peer
+		for len(request.announcers) > 0 && next == nil {
+			candidate := request.announcers[0]
+			request.announcers = request.announcers[1:]
+			if node.hasPeer(candidate) {
+				next = candidate
+			}
+		}
+		if next == nil && retrySame && request.attempts < maxInventoryAttempts && node.hasPeer(request.peer) {
+			next = request.peer
+		}
+		if next == nil {
+			delete(node.inFlight, key)
+			continue
+		}
+		request.peer = next
+		request.time = time.Now()
+		request.attempts++
+		pending = append(pending, getData{next, request.inv})
+	}
+	node.relayMutex.Unlock()
+
+	for _, item := range pending {
+		item.peer.SendMessage(cmdGetData, serializeInv([]*InvVect{item.inv}))
+	}
+}
+
+// 定时检查超时的请求
+func (node *Node) relayLoop() {
+	defer node.wg.Done()
+	ticker := time.NewTicker(relayTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			node.retryInventory(func(request *inventoryRequest) bool {
+				return time.Since(request.time) > inventoryRequestTimeout
+			}, true)
+		case <-node.quit:
+			return
+		}
+	}
+}
+
+// 向所有已握手且尚未拥有该对象的节点发送inv消息
+func (node *Node) broadcastInv(inv *InvVect) {
+	payload := serializeInv([]*InvVect{inv})
+	for _, peer := range node.Peers() {
+		if !peer.handshaked() || peer.KnowsInventory(inv) {
+			continue
+		}
+		peer.AddKnownInventory(inv)
+		peer.SendMessage(cmdInv, payload)
+	}
+}
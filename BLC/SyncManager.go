@@ -0,0 +1,476 @@
+package BLC
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 区块同步管理文件
+// 连接到高度更高的节点时进行初始同步：
+// 1. 先从同步节点下载区块头，并验证区块头链的工作量证明与连续性
+// 2. 再从所有高度足够的节点并行下载区块
+// 3. 按高度顺序通过正常的区块验证流程(ProcessBlock)接入区块链
+
+const (
+	// 每个节点同时请求的最大区块数
+	maxBlocksInFlightPerPeer = 16
+	// 区块请求超时时间，超时后改由其他节点下载
+	blockRequestTimeout = 20 * time.Second
+	// 区块头请求超时时间，超时后更换同步节点
+	headersRequestTimeout = 30 * time.Second
+	// 检查超时的间隔
+	syncTickInterval = 5 * time.Second
+)
+
+// 正在下载的区块
+type blockRequest struct {
+	peer *Peer
+	time time.Time
+}
+
+// 同步管理器
+type SyncManager struct {
+	node *Node
+
+	mutex        sync.Mutex
+	syncing      bool
+	syncPeer     *Peer
+	headersTime  time.Time                 // 最近一次请求区块头的时间
+	headers      []*BlockHeader            // 已验证的区块头链
+	headerIndex  map[string]int            // 区块哈希->区块头索引
+	next         int                       // 下一个待接入的区块头索引
+	requested    map[string]*blockRequest  // 正在下载的区块
+	received     map[string]*Block         // 已下载、等待按顺序接入的区块
+	senders      map[string]*Peer          // 已下载区块的发送节点
+	notFound     map[string]map[*Peer]bool // 回复没有该区块的节点(位于其他分叉)，不再向其请求
+	lastProgress int                       // 最近一次输出的进度(百分比)
+
+	connectMutex sync.Mutex // 保证区块按顺序接入
+	quit         chan struct{}
+}
+
+// 创建同步管理器
+func NewSyncManager(node *Node) *SyncManager {
+	return &SyncManager{
+		node:        node,
+		requested:   make(map[string]*blockRequest),
+		received:    make(map[string]*Block),
+		senders:     make(map[string]*Peer),
+		notFound:    make(map[string]map[*Peer]bool),
+		headerIndex: make(map[string]int),
+		quit:        make(chan struct{}),
+	}
+}
+
+// 启动超时检查
+func (sm *SyncManager) Start() {
+	sm.node.wg.Add(1)
+	go sm.tickLoop()
+}
+
+// 停止同步管理器
+func (sm *SyncManager) Stop() {
+	close(sm.quit)
+}
+
+// 同步进度：已接入的区块数、需要同步的区块总数、是否正在同步
+func (sm *SyncManager) Progress() (int, int, bool) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	return sm.next, len(sm.headers), sm.syncing
+}
+
+// 是否正在同步
+func (sm *SyncManager) IsSyncing() bool {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	return sm.syncing
+}
+
+// 新节点完成握手
+func (sm *SyncManager) NewPeer(peer *Peer) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	if sm.syncing {
+		// 正在同步，新节点参与区块下载
+		sm.assignDownloads()
+		return nil
+	}
+	if peer.Height() > sm.node.height() {
+		return sm.startSync(peer)
+	}
+	return nil
+}
+
+// 节点断开连接：重新分配其正在下载的区块，必要时更换同步节点
+func (sm *SyncManager) DonePeer(peer *Peer) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	for hash, request := range sm.requested {
+		if request.peer == peer {
+			delete(sm.requested, hash)
+		}
+	}
+	if sm.syncing && sm.syncPeer == peer {
+		sm.syncPeer = nil
+		sm.pickSyncPeer()
+		return
+	}
+	if sm.syncing {
+		sm.assignDownloads()
+	}
+}
+
+// 开始与指定节点同步：请求区块头
+// 调用时需持有sm.mutex
+func (sm *SyncManager) startSync(peer *Peer) error {
+	sm.syncing = true
+	sm.syncPeer = peer
+	sm.headers = nil
+	sm.headerIndex = make(map[string]int)
+	sm.next = 0
+	sm.lastProgress = 0
+	sm.requested = make(map[string]*blockRequest)
+	sm.received = make(map[string]*Block)
+	sm.senders = make(map[string]*Peer)
+	sm.notFound = make(map[string]map[*Peer]bool)
+	netLog.Infof("开始与节点 [%s] 同步，对方高度 [%d]，本地高度 [%d]\n", peer, peer.Height(), sm.node.height())
+	return sm.requestHeaders()
+}
+
+// 请求区块头：从已验证的最后一个区块头之后开始
+// 调用时需持有sm.mutex
+func (sm *SyncManager) requestHeaders() error {
+	var locator [][]byte
+	if len(sm.headers) > 0 {
+		locator = [][]byte{sm.headers[len(sm.headers)-1].Hash}
+	} else {
+		var err error
+		sm.node.chainMutex.Lock()
+		locator, err = sm.node.blockchain.BlockLocator()
+		sm.node.chainMutex.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	sm.headersTime = time.Now()
+	return sm.syncPeer.SendMessage(cmdGetHdrs, serializeHashes(locator))
+}
+
+// 选择高度最高的节点作为新的同步节点，没有更高的节点时结束同步
+// 调用时需持有sm.mutex
+func (sm *SyncManager) pickSyncPeer() {
+	var best *Peer
+	for _, peer := range sm.node.Peers() {
+		if peer.handshaked() && (best == nil || peer.Height() > best.Height()) {
+			best = peer
+		}
+	}
+	height := sm.node.height()
+	if best == nil || best.Height() <= height {
+		sm.finishSync()
+		return
+	}
+	// 已下载的区块头仍然有效，新的同步节点从最后一个区块头之后继续
+	sm.syncPeer = best
+	netLog.Infof("更换同步节点为 [%s]\n", best)
+	if err := sm.requestHeaders(); err != nil {
+		netLog.Warnf("请求区块头失败：%v\n", err)
+	}
+}
+
+// 结束同步
+// 调用时需持有sm.mutex
+func (sm *SyncManager) finishSync() {
+	if sm.syncing {
+		netLog.Infof("同步结束，当前高度 [%d]\n", sm.node.height())
+	}
+	sm.syncing = false
+	sm.syncPeer = nil
+	sm.headers = nil
+	sm.headerIndex = make(map[string]int)
+	sm.next = 0
+	sm.requested = make(map[string]*blockRequest)
+	sm.received = make(map[string]*Block)
+	sm.senders = make(map[string]*Peer)
+	sm.notFound = make(map[string]map[*Peer]bool)
+}
+
+// 处理headers消息
+func (sm *SyncManager) HandleHeaders(peer *Peer, headers []*BlockHeader) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	if !sm.syncing || peer != sm.syncPeer {
+		// 不是向该节点请求的区块头，忽略
+		return nil
+	}
+	if err := sm.validateHeaders(headers); err != nil {
+		sm.syncPeer = nil
+		sm.pickSyncPeer()
+		return misbehavior(banScoreInvalidHeaders, fmt.Errorf("invalid headers: %v", err))
+	}
+	for _, header := range headers {
+		sm.headerIndex[hex.EncodeToString(header.Hash)] = len(sm.headers)
+		sm.headers = append(sm.headers, header)
+	}
+	if len(headers) > 0 {
+		peer.updateHeight(headers[len(headers)-1].Height)
+	}
+
+	// 区块头未下载完，继续请求
+	if len(headers) == maxHeadersPerMsg {
+		return sm.requestHeaders()
+	}
+	sm.headersTime = time.Time{}
+	netLog.Infof("区块头下载完成，共 [%d] 个区块需要同步\n", len(sm.headers)-sm.next)
+	if sm.next >= len(sm.headers) {
+		sm.finishSync()
+		return nil
+	}
+	sm.assignDownloads()
+	return nil
+}
+
+// 验证区块头链：工作量证明有效，与前一个区块头(或本地区块)相连且高度连续
+// 调用时需持有sm.mutex
+func (sm *SyncManager) validateHeaders(headers []*BlockHeader) error {
+	var prevHash []byte
+	var prevHeight int64
+	if len(sm.headers) > 0 {
+		last := sm.headers[len(sm.headers)-1]
+		prevHash, prevHeight = last.Hash, last.Height
+	} else if len(headers) > 0 {
+		first := headers[0]
+		if len(first.PrevBlockHash) == 0 {
+			// 创世区块只能同步到空链
+			sm.node.chainMutex.Lock()
+			empty := sm.node.blockchain.IsEmpty()
+			sm.node.chainMutex.Unlock()
+			if !empty {
+				return errors.New("genesis block does not match the local chain")
+			}
+		} else {
+			parent := sm.node.blockchain.GetBlock(first.PrevBlockHash)
+			if parent == nil {
+				return fmt.Errorf("header [%x] does not connect to the local chain", first.Hash)
+			}
+			prevHash, prevHeight = parent.Hash, parent.Height
+		}
+	}
+	for _, header := range headers {
+		if !bytes.Equal(header.PrevBlockHash, prevHash) || header.Height != prevHeight+1 {
+			return fmt.Errorf("header [%x] does not follow the previous header", header.Hash)
+		}
+		if !header.ValidatePoW() {
+			return fmt.Errorf("header [%x] has invalid proof of work", header.Hash)
+		}
+		prevHash, prevHeight = header.Hash, header.Height
+	}
+	return nil
+}
+
+// 将尚未下载的区块分配给高度足够的节点
+// 调用时需持有sm.mutex
+func (sm *SyncManager) assignDownloads() {
+	inFlight := make(map[*Peer]int)
+	for _, request := range sm.requested {
+		inFlight[request.peer]++
+	}
+	requests := make(map[*Peer][]*InvVect)
+	peers := sm.node.Peers()
+	peerIndex := 0
+	for _, header := range sm.headers[sm.next:] {
+		key := hex.EncodeToString(header.Hash)
+		if sm.requested[key] != nil || sm.received[key] != nil {
+			continue
+		}
+		// 轮流选择有空闲且拥有该区块的节点
+		var chosen *Peer
+		for i := 0; i < len(peers); i++ {
+			peer := peers[(peerIndex+i)%len(peers)]
+			if peer.handshaked() && peer.Height() >= header.Height && inFlight[peer] < maxBlocksInFlightPerPeer && !sm.notFound[key][peer] {
+				chosen = peer
+				peerIndex = (peerIndex + i + 1) % len(peers)
+				break
+			}
+		}
+		if chosen == nil {
+			break
+		}
+		inFlight[chosen]++
+		sm.requested[key] = &blockRequest{peer: chosen, time: time.Now()}
+		requests[chosen] = append(requests[chosen], &InvVect{InvTypeBlock, header.Hash})
+	}
+	for peer, invs := range requests {
+		if err := peer.SendMessage(cmdGetData, serializeInv(invs)); err != nil {
+			netLog.Warnf("向节点 [%s] 请求区块失败：%v\n", peer, err)
+		}
+	}
+}
+
+// 处理notfound消息：对方没有请求的区块，改由其他节点下载
+func (sm *SyncManager) HandleNotFound(peer *Peer, invs []*InvVect) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	if !sm.syncing {
+		return
+	}
+	var reassign bool
+	for _, inv := range invs {
+		key := hex.EncodeToString(inv.Hash)
+		if request, ok := sm.requested[key]; inv.Type == InvTypeBlock && ok && request.peer == peer {
+			delete(sm.requested, key)
+			if sm.notFound[key] == nil {
+				sm.notFound[key] = make(map[*Peer]bool)
+			}
+			sm.notFound[key][peer] = true
+			reassign = true
+		}
+	}
+	if reassign {
+		sm.assignDownloads()
+	}
+}
+
+// 处理同步过程中请求的区块，返回该区块是否由同步管理器处理
+func (sm *SyncManager) HandleBlock(peer *Peer, block *Block) bool {
+	sm.mutex.Lock()
+	key := hex.EncodeToString(block.Hash)
+	// 包括请求超时后才到达的区块
+	index, ok := sm.headerIndex[key]
+	if !sm.syncing || !ok || index < sm.next {
+		sm.mutex.Unlock()
+		return false
+	}
+	delete(sm.requested, key)
+	sm.received[key] = block
+	sm.senders[key] = peer
+	sm.mutex.Unlock()
+
+	sm.connectBlocks()
+
+	sm.mutex.Lock()
+	if sm.syncing {
+		sm.assignDownloads()
+	}
+	sm.mutex.Unlock()
+	return true
+}
+
+// 按高度顺序接入已下载的区块，区块无效时对其发送节点记录违规
+func (sm *SyncManager) connectBlocks() {
+	sm.connectMutex.Lock()
+	defer sm.connectMutex.Unlock()
+	for {
+		sm.mutex.Lock()
+		if !sm.syncing || sm.next >= len(sm.headers) {
+			sm.mutex.Unlock()
+			return
+		}
+		header := sm.headers[sm.next]
+		key := hex.EncodeToString(header.Hash)
+		block := sm.received[key]
+		if block == nil {
+			sm.mutex.Unlock()
+			return
+		}
+		sender := sm.senders[key]
+		delete(sm.received, key)
+		delete(sm.senders, key)
+		sm.mutex.Unlock()
+
+		_, err := sm.node.ProcessBlock(block)
+		if err != nil && err != ErrBlockExists {
+			// 区块与区块头不符或验证失败，放弃本次同步
+			sm.mutex.Lock()
+			sm.finishSync()
+			sm.mutex.Unlock()
+			sm.node.misbehaving(sender, banScoreInvalidBlock, fmt.Sprintf("invalid block [%x]: %v", block.Hash, err))
+			return
+		}
+
+		sm.mutex.Lock()
+		sm.next++
+		sm.reportProgress()
+		if sm.next >= len(sm.headers) {
+			sm.finishSync()
+			// 同步期间可能出现了更高的节点
+			sm.pickNextSync()
+		}
+		sm.mutex.Unlock()
+	}
+}
+
+// 同步完成后，如果还有更高的节点则继续同步
+// 调用时需持有sm.mutex
+func (sm *SyncManager) pickNextSync() {
+	height := sm.node.height()
+	for _, peer := range sm.node.Peers() {
+		if peer.handshaked() && peer.Height() > height {
+			if err := sm.startSync(peer); err != nil {
+				netLog.Warnf("请求区块头失败：%v\n", err)
+			}
+			return
+		}
+	}
+}
+
+// 输出同步进度，每10%输出一次
+// 调用时需持有sm.mutex
+func (sm *SyncManager) reportProgress() {
+	total := len(sm.headers)
+	if total == 0 {
+		return
+	}
+	percent := sm.next * 100 / total
+	if percent/10 > sm.lastProgress/10 || sm.next == total {
+		sm.lastProgress = percent
+		netLog.Infof("同步进度 [%d/%d] %d%%，当前高度 [%d]\n", sm.next, total, percent, sm.headers[sm.next-1].Height)
+	}
+}
+
+// 定时检查超时的请求
+func (sm *SyncManager) tickLoop() {
+	defer sm.node.wg.Done()
+	ticker := time.NewTicker(syncTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			sm.checkTimeouts()
+		case <-sm.quit:
+			return
+		}
+	}
+}
+
+// 超时的区块请求改由其他节点下载，区块头请求超时则更换同步节点
+func (sm *SyncManager) checkTimeouts() {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	if !sm.syncing {
+		return
+	}
+	now := time.Now()
+	if sm.syncPeer != nil && !sm.headersTime.IsZero() && now.Sub(sm.headersTime) > headersRequestTimeout {
+		netLog.Warnf("节点 [%s] 区块头请求超时\n", sm.syncPeer)
+		sm.syncPeer = nil
+		sm.pickSyncPeer()
+		return
+	}
+	var expired int
+	for hash, request := range sm.requested {
+		if now.Sub(request.time) > blockRequestTimeout {
+			delete(sm.requested, hash)
+			expired++
+		}
+	}
+	if expired > 0 {
+		netLog.Warnf("[%d] 个区块请求超时，重新分配\n", expired)
+		sm.assignDownloads()
+	}
+}
@@ -5,8 +5,9 @@ import (
 	"crypto/sha256"
 	"encoding/gob"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"sort"
 )
 
 // 交易管理文件
@@ -18,6 +19,18 @@ type Transaction struct {
 	Vouts  []*TxOutput // 输出列表
 }
 
+// 交易哈希基于gob编码计算，而gob编码中的类型编号取决于进程中各类型首次编码的顺序
+// 程序启动时先编码一次交易，保证交易相关类型的编号固定，不受其他gob编码(例如节点地址)的影响
+func init() {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(&Transaction{}); err != nil {
+		utxoLog.Panicf("init transaction encoding failed %v\n", err)
+	}
+}
+
+// coinbase交易奖励
+const coinbaseReward = 10 * CoinUnit
+
 // 实现coinbase交易
 func NewCoinbaseTransaction(address string) *Transaction {
 	// 输入
@@ -34,7 +47,7 @@ func NewCoinbaseTransaction(address string) *Transaction {
 	// value：
 	// address：
 	txOutput := &TxOutput{
-		Value:        10,
+		Value:        coinbaseReward,
 		ScriptPubkey: address,
 	}
 	txCoinbase := &Transaction{
@@ -54,7 +67,7 @@ func (tx *Transaction) HashTransaction() {
 	// 设置编码对象
 	encoder := gob.NewEncoder(&result)
 	if err := encoder.Encode(tx); err != nil {
-		log.Panicf("tx Hash encoded failed %v\n", err)
+		utxoLog.Panicf("tx Hash encoded failed %v\n", err)
 	}
 
 	// 生成哈希值
@@ -63,18 +76,47 @@ func (tx *Transaction) HashTransaction() {
 }
 
 // 生成普通转账交易
-func NewSimpleTransaciton(from string, to string, amount int, blockchain *BlockChain, txs []*Transaction) *Transaction {
+func NewSimpleTransaciton(from string, to string, amount Amount, selector CoinSelector, blockchain *BlockChain, txs []*Transaction) (*Transaction, error) {
+	return NewMultiOutputTransaction(from, map[string]Amount{to: amount}, selector, blockchain, txs)
+}
+
+// 生成一对多转账交易
+// outputs:目标地址->转账金额，所有目标共用一次UTXO查找，并只生成一个找零输出
+// selector:选币策略
+// 金额无效或余额不足时返回错误
+func NewMultiOutputTransaction(from string, outputs map[string]Amount, selector CoinSelector, blockchain *BlockChain, txs []*Transaction) (*Transaction, error) {
 	var txInputs []*TxInput
 	var txOutputs []*TxOutput
 
+	// 按地址排序，保证输出顺序确定
+	var addresses []string
+	var amount Amount
+	for address, value := range outputs {
+		if value <= 0 || !value.IsValid() {
+			return nil, fmt.Errorf("invalid amount [%s] to [%s]", value, address)
+		}
+		addresses = append(addresses, address)
+		var err error
+		if amount, err = amount.Add(value); err != nil {
+			return nil, fmt.Errorf("sum of outputs failed: %v", err)
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, errors.New("transaction needs at least one output")
+	}
+	sort.Strings(addresses)
+
 	// 获取UTXO
-	money, utoxsDic := blockchain.FindSpendableUTXO(from, amount, txs)
-	fmt.Printf("money:%v\n", money)
+	money, utoxsDic, err := blockchain.FindSpendableUTXO(from, amount, txs, selector)
+	if err != nil {
+		return nil, err
+	}
+	utxoLog.Debugf("地址 [%s] 选中的UTXO金额 [%s]\n", from, money)
 	// 输入
 	for txHash, indexArry := range utoxsDic {
 		txHashBytes, err := hex.DecodeString(txHash)
 		if err != nil {
-			log.Panicf("decode string to []byte failed! %v\n", err)
+			return nil, fmt.Errorf("decode utxo hash [%s] failed: %v", txHash, err)
 		}
 
 		// 遍历索引列表
@@ -84,23 +126,58 @@ func NewSimpleTransaciton(from string, to string, amount int, blockchain *BlockC
 	}
 
 	// 输出（源）
-	txOutput := &TxOutput{amount, to}
-	txOutputs = append(txOutputs, txOutput)
+	for _, address := range addresses {
+		txOutputs = append(txOutputs, &TxOutput{outputs[address], address, nil})
+	}
 
-	// 输出（找零）
+	// 输出（找零），金额恰好相等时不产生找零输出
+	if money < amount {
+		return nil, fmt.Errorf("insufficient funds in [%s]: selected [%s], amount [%s]", from, money, amount)
+	}
 	if money > amount {
-		txOutput = &TxOutput{money - amount, from}
+		txOutput := &TxOutput{money - amount, from, nil}
 		txOutputs = append(txOutputs, txOutput)
-	} else {
-		log.Panicf("余额不足...\n")
 	}
 
 	tx := Transaction{nil, txInputs, txOutputs}
 	tx.HashTransaction()
-	return &tx
+	return &tx, nil
 }
 
 // 判断指定的交易是否是一个coinbase交易
 func (tx *Transaction) IsCoinbaseTransaction() bool {
 	return tx.Vins[0].Vout == -1 && len(tx.Vins[0].TxHash) == 0
 }
+
+// 输出交易详情
+func (tx *Transaction) PrintTransaction() {
+	fmt.Printf("\t\t----------------------------\n")
+	fmt.Printf("\t\ttx-hash: %x\n", tx.TxHash)
+	fmt.Printf("\t\t输入...\n")
+	for _, vin := range tx.Vins {
+		fmt.Printf("\t\tvin-txHash: %x\n", vin.TxHash)
+		fmt.Printf("\t\tvin-vout: %v\n", vin.Vout)
+		fmt.Printf("\t\tvin-scriptSig: %s\n", vin.ScriptSig)
+	}
+	fmt.Printf("\t\t输出...\n")
+	for _, vout := range tx.Vouts {
+		if vout.IsDataOutput() {
+			fmt.Printf("\t\tvout-data: %s\n", vout.DataString())
+			continue
+		}
+		fmt.Printf("\t\tvout-value: %s\n", vout.Value)
+		fmt.Printf("\t\tvout-scriptPubkey: %s\n", vout.ScriptPubkey)
+	}
+}
+
+// 为交易添加一个数据输出，并重新生成交易哈希
+func (tx *Transaction) AddDataOutput(data []byte) error {
+	txOutput, err := NewDataOutput(data)
+	if err != nil {
+		return err
+	}
+	tx.Vouts = append(tx.Vouts, txOutput)
+	tx.TxHash = nil
+	tx.HashTransaction()
+	return nil
+}
@@ -0,0 +1,150 @@
+package BLC
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewMultiOutputTransaction(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+
+	tx, err := NewMultiOutputTransaction("alice", map[string]Amount{"carol": 2 * CoinUnit, "bob": 3 * CoinUnit}, nil, blockchain, nil)
+	if err != nil {
+		t.Fatalf("NewMultiOutputTransaction error: %v", err)
+	}
+	// 输出按地址排序，找零在最后
+	var got []string
+	for _, out := range tx.Vouts {
+		got = append(got, out.ScriptPubkey+":"+out.Value.String())
+	}
+	if want := "bob:3 carol:2 alice:5"; strings.Join(got, " ") != want {
+		t.Fatalf("outputs = %v, want %s", got, want)
+	}
+	if err := blockchain.VerifyTransaction(tx, nil); err != nil {
+		t.Fatalf("VerifyTransaction error: %v", err)
+	}
+
+	// 金额恰好相等时不产生找零
+	tx, err = NewSimpleTransaciton("alice", "bob", coinbaseReward, nil, blockchain, nil)
+	if err != nil {
+		t.Fatalf("NewSimpleTransaciton error: %v", err)
+	}
+	if len(tx.Vouts) != 1 {
+		t.Fatalf("exact spend has %d outputs, want 1", len(tx.Vouts))
+	}
+}
+
+func TestNewMultiOutputTransactionInsufficientFunds(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+
+	tests := []struct {
+		from     string
+		outputs  map[string]Amount
+		selector CoinSelector
+	}{
+		{"alice", map[string]Amount{"bob": coinbaseReward + 1}, nil},
+		{"alice", map[string]Amount{"bob": 6 * CoinUnit, "carol": 5 * CoinUnit}, SmallestFirstSelector{}},
+		{"bob", map[string]Amount{"alice": 1}, nil},
+	}
+	for _, test := range tests {
+		tx, err := NewMultiOutputTransaction(test.from, test.outputs, test.selector, blockchain, nil)
+		if err == nil || !strings.HasPrefix(err.Error(), "insufficient funds") {
+			t.Errorf("NewMultiOutputTransaction(%s, %v) = %v, %v, want insufficient funds error", test.from, test.outputs, tx, err)
+		}
+	}
+
+	// 没有Fallback的分支定界找不到精确组合
+	if _, err := NewSimpleTransaciton("alice", "bob", 3*CoinUnit, BranchAndBoundSelector{}, blockchain, nil); err == nil {
+		t.Fatal("bnb without fallback succeeded without an exact match")
+	}
+
+	// 缓存中的交易已花费全部余额
+	spent, err := NewSimpleTransaciton("alice", "bob", coinbaseReward, nil, blockchain, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSimpleTransaciton("alice", "carol", 1, nil, blockchain, []*Transaction{spent}); err == nil {
+		t.Fatal("spending an output already spent by a cached transaction succeeded")
+	}
+}
+
+func TestMineNewBlockInsufficientFunds(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+	tip := blockchain.Tip
+
+	if block, err := blockchain.MineNewBlock([]string{"alice"}, []string{"bob"}, []string{"11"}, nil, nil); err == nil {
+		t.Fatalf("MineNewBlock with insufficient funds mined block %x", block.Hash)
+	}
+	// 第二笔交易引用第一笔交易的找零，总额超过余额
+	_, err := blockchain.MineNewBlock([]string{"alice", "alice"}, []string{"bob", "carol"}, []string{"6", "5"}, nil, nil)
+	if err == nil {
+		t.Fatal("MineNewBlock spending more than the balance succeeded")
+	}
+	if _, err := blockchain.MineMultiOutputBlock("bob", map[string]Amount{"alice": 1}, nil, nil); err == nil {
+		t.Fatal("MineMultiOutputBlock with insufficient funds succeeded")
+	}
+	if string(blockchain.Tip) != string(tip) {
+		t.Fatal("failed sends changed the tip")
+	}
+
+	block, err := blockchain.MineNewBlock([]string{"alice", "alice"}, []string{"bob", "carol"}, []string{"6", "4"}, nil, []byte("memo"))
+	if err != nil {
+		t.Fatalf("MineNewBlock error: %v", err)
+	}
+	if len(block.Txs) != 2 || !block.Txs[0].Vouts[len(block.Txs[0].Vouts)-1].IsDataOutput() {
+		t.Fatal("MineNewBlock did not add the data output to the first transaction")
+	}
+	for address, want := range map[string]Amount{"alice": 0, "bob": 6 * CoinUnit, "carol": 4 * CoinUnit} {
+		if got := blockchain.getBalance(address); got != want {
+			t.Errorf("balance of [%s] = %s, want %s", address, got, want)
+		}
+	}
+}
+
+func TestNewMultiOutputTransactionInvalidAmount(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+
+	tests := []map[string]Amount{
+		nil,
+		{"bob": 0},
+		{"bob": -1},
+		{"bob": MaxAmount + 1},
+		{"bob": CoinUnit, "carol": -CoinUnit},
+		{"bob": MaxAmount, "carol": MaxAmount},
+	}
+	for _, outputs := range tests {
+		if tx, err := NewMultiOutputTransaction("alice", outputs, nil, blockchain, nil); err == nil {
+			t.Errorf("NewMultiOutputTransaction(%v) = %x, want error", outputs, tx.TxHash)
+		}
+	}
+}
+
+func TestMineNewBlockInvalidArguments(t *testing.T) {
+	blockchain := newTestChain(t, "alice")
+	tip := blockchain.Tip
+
+	tests := []struct {
+		from, to, amount []string
+	}{
+		{nil, nil, nil},
+		{[]string{"alice"}, []string{"bob", "carol"}, []string{"1"}},
+		{[]string{"alice"}, []string{"bob"}, nil},
+		{[]string{"alice"}, []string{"bob"}, []string{"0"}},
+		{[]string{"alice"}, []string{"bob"}, []string{"-1"}},
+		{[]string{"alice"}, []string{"bob"}, []string{"abc"}},
+		{[]string{"alice"}, []string{"bob"}, []string{"0.000000001"}},
+		{[]string{"alice"}, []string{"bob"}, []string{"21000001"}},
+		{[]string{"alice", "alice"}, []string{"bob", "carol"}, []string{"1", "0"}},
+	}
+	for _, test := range tests {
+		if _, err := blockchain.MineNewBlock(test.from, test.to, test.amount, nil, nil); err == nil {
+			t.Errorf("MineNewBlock(%v, %v, %v) succeeded", test.from, test.to, test.amount)
+		}
+	}
+	if _, err := blockchain.MineMultiOutputBlock("alice", map[string]Amount{"bob": 0}, nil, nil); err == nil {
+		t.Error("MineMultiOutputBlock with a zero amount succeeded")
+	}
+	if string(blockchain.Tip) != string(tip) {
+		t.Fatal("rejected sends changed the tip")
+	}
+}
@@ -0,0 +1,31 @@
+package BLC
+
+import (
+	"net"
+	"time"
+)
+
+// 网络传输管理文件
+// 节点通过Transport监听端口与建立连接，默认使用TCP，测试时可替换为模拟网络
+
+// 网络传输
+type Transport interface {
+	// 监听指定地址
+	Listen(addr string) (net.Listener, error)
+	// 连接指定地址
+	Dial(addr string, timeout time.Duration) (net.Conn, error)
+}
+
+// TCP传输
+type tcpTransport struct{}
+
+func (tcpTransport) Listen(addr string) (net.Listener, error) {
+	return net.Listen("tcp", addr)
+}
+
+func (tcpTransport) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("tcp", addr, timeout)
+}
+
+// 默认的TCP传输
+var TCPTransport Transport = tcpTransport{}
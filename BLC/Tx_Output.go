@@ -1,14 +1,58 @@
 package BLC
 
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
 // 交易输出管理
 
+// 数据输出附加数据的最大长度
+const MaxDataOutputSize = 80
+
 // 输出结构
 type TxOutput struct {
-	Value        int    // 金额
+	Value        Amount // 金额(最小单位)
 	ScriptPubkey string // 用户名（地址）
+	Data         []byte // 附加数据(仅数据输出使用)
+}
+
+// 创建数据输出
+// 数据输出金额为0、不属于任何地址，因此不可花费(类似OP_RETURN)
+func NewDataOutput(data []byte) (*TxOutput, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("data output payload is empty")
+	}
+	if len(data) > MaxDataOutputSize {
+		return nil, fmt.Errorf("data output payload size [%d] exceeds [%d]", len(data), MaxDataOutputSize)
+	}
+	return &TxOutput{0, "", data}, nil
+}
+
+// 判断当前输出是否为数据输出
+func (txOutput *TxOutput) IsDataOutput() bool {
+	return len(txOutput.Data) > 0
 }
 
 // 验证当前输出是否属于指定地址
+// 数据输出不属于任何地址
 func (txOutput *TxOutput) CheckPubkeyWithAddress(address string) bool {
-	return address == txOutput.ScriptPubkey
+	return !txOutput.IsDataOutput() && address == txOutput.ScriptPubkey
+}
+
+// 数据输出的可读形式，能按文本显示时同时给出文本
+func (txOutput *TxOutput) DataString() string {
+	if utf8.Valid(txOutput.Data) {
+		printable := true
+		for _, r := range string(txOutput.Data) {
+			if r < 0x20 || r == 0x7f {
+				printable = false
+				break
+			}
+		}
+		if printable {
+			return fmt.Sprintf("%x (%q)", txOutput.Data, txOutput.Data)
+		}
+	}
+	return fmt.Sprintf("%x", txOutput.Data)
 }
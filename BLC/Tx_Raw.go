@@ -0,0 +1,218 @@
+package BLC
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// 原始交易管理
+// 交易的规范编码格式（与gob不同，字段顺序与长度固定，便于离线传递和签名）：
+//   vins数量(uvarint)
+//     交易哈希(uvarint长度+字节) 输出索引(int64) 签名(uvarint长度+字节)
+//   vouts数量(uvarint)
+//     金额(int64) 地址(uvarint长度+字节) 附加数据(uvarint长度+字节)
+
+// 原始交易单个字段的最大长度
+const maxRawFieldSize = 1 << 16
+
+// 原始交易的输入参数
+type RawTxInput struct {
+	TxID string `json:"txid"` // 引用的交易哈希(hex)
+	Vout int    `json:"vout"` // 引用的输出索引
+}
+
+// 根据指定的输入输出创建未签名的原始交易
+func NewRawTransaction(inputs []RawTxInput, outputs map[string]Amount) (*Transaction, error) {
+	var txInputs []*TxInput
+	var txOutputs []*TxOutput
+
+	for _, input := range inputs {
+		txHash, err := hex.DecodeString(input.TxID)
+		if err != nil || len(txHash) == 0 {
+			return nil, fmt.Errorf("invalid txid [%s]", input.TxID)
+		}
+		if input.Vout < 0 {
+			return nil, fmt.Errorf("invalid vout [%d]", input.Vout)
+		}
+		// 签名为空，表示尚未签名
+		txInputs = append(txInputs, &TxInput{txHash, input.Vout, ""})
+	}
+
+	var addresses []string
+	for address := range outputs {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		if outputs[address] <= 0 || !outputs[address].IsValid() {
+			return nil, fmt.Errorf("invalid amount [%s] to [%s]", outputs[address], address)
+		}
+		txOutputs = append(txOutputs, &TxOutput{outputs[address], address, nil})
+	}
+
+	if len(txInputs) == 0 || len(txOutputs) == 0 {
+		return nil, errors.New("raw transaction needs at least one input and one output")
+	}
+
+	tx := &Transaction{nil, txInputs, txOutputs}
+	tx.HashTransaction()
+	return tx, nil
+}
+
+// 使用指定地址对所有未签名的输入签名（模拟签名，与TxInput.ScriptSig一致）
+// 返回本次签名的输入数量
+func (tx *Transaction) Sign(address string) int {
+	var signed int
+	for _, in := range tx.Vins {
+		if in.ScriptSig == "" {
+			in.ScriptSig = address
+			signed++
+		}
+	}
+	// 签名改变了交易内容，重新生成交易哈希
+	tx.TxHash = nil
+	tx.HashTransaction()
+	return signed
+}
+
+// 判断交易的所有输入是否都已签名
+func (tx *Transaction) IsSigned() bool {
+	for _, in := range tx.Vins {
+		if in.ScriptSig == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// 交易的规范编码
+func (tx *Transaction) SerializeRaw() []byte {
+	var buffer bytes.Buffer
+	writeUvarint(&buffer, uint64(len(tx.Vins)))
+	for _, in := range tx.Vins {
+		writeVarBytes(&buffer, in.TxHash)
+		buffer.Write(IntoHex(int64(in.Vout)))
+		writeVarBytes(&buffer, []byte(in.ScriptSig))
+	}
+	writeUvarint(&buffer, uint64(len(tx.Vouts)))
+	for _, out := range tx.Vouts {
+		buffer.Write(IntoHex(int64(out.Value)))
+		writeVarBytes(&buffer, []byte(out.ScriptPubkey))
+		writeVarBytes(&buffer, out.Data)
+	}
+	return buffer.Bytes()
+}
+
+// 规范编码的反序列化，并重新生成交易哈希
+func DeserializeRawTransaction(data []byte) (*Transaction, error) {
+	reader := bytes.NewReader(data)
+	tx := &Transaction{}
+
+	vinCount, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read vin count failed: %v", err)
+	}
+	if vinCount > uint64(len(data)) {
+		return nil, fmt.Errorf("invalid vin count [%d]", vinCount)
+	}
+	for i := uint64(0); i < vinCount; i++ {
+		txHash, err := readVarBytes(reader)
+		if err != nil {
+			return nil, fmt.Errorf("read vin %d txhash failed: %v", i, err)
+		}
+		vout, err := readInt64(reader)
+		if err != nil {
+			return nil, fmt.Errorf("read vin %d vout failed: %v", i, err)
+		}
+		scriptSig, err := readVarBytes(reader)
+		if err != nil {
+			return nil, fmt.Errorf("read vin %d scriptSig failed: %v", i, err)
+		}
+		tx.Vins = append(tx.Vins, &TxInput{txHash, int(vout), string(scriptSig)})
+	}
+
+	voutCount, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read vout count failed: %v", err)
+	}
+	if voutCount > uint64(len(data)) {
+		return nil, fmt.Errorf("invalid vout count [%d]", voutCount)
+	}
+	for i := uint64(0); i < voutCount; i++ {
+		value, err := readInt64(reader)
+		if err != nil {
+			return nil, fmt.Errorf("read vout %d value failed: %v", i, err)
+		}
+		scriptPubkey, err := readVarBytes(reader)
+		if err != nil {
+			return nil, fmt.Errorf("read vout %d scriptPubkey failed: %v", i, err)
+		}
+		data, err := readVarBytes(reader)
+		if err != nil {
+			return nil, fmt.Errorf("read vout %d data failed: %v", i, err)
+		}
+		tx.Vouts = append(tx.Vouts, &TxOutput{Amount(value), string(scriptPubkey), data})
+	}
+
+	if reader.Len() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after transaction", reader.Len())
+	}
+	if len(tx.Vins) == 0 || len(tx.Vouts) == 0 {
+		return nil, errors.New("transaction has no inputs or outputs")
+	}
+	tx.HashTransaction()
+	return tx, nil
+}
+
+// 交易转hex字符串
+func EncodeRawTransaction(tx *Transaction) string {
+	return hex.EncodeToString(tx.SerializeRaw())
+}
+
+// hex字符串转交易
+func DecodeRawTransaction(rawHex string) (*Transaction, error) {
+	data, err := hex.DecodeString(rawHex)
+	if err != nil {
+		return nil, fmt.Errorf("decode hex failed: %v", err)
+	}
+	return DeserializeRawTransaction(data)
+}
+
+func writeUvarint(buffer *bytes.Buffer, n uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	buffer.Write(buf[:binary.PutUvarint(buf[:], n)])
+}
+
+func writeVarBytes(buffer *bytes.Buffer, data []byte) {
+	writeUvarint(buffer, uint64(len(data)))
+	buffer.Write(data)
+}
+
+func readVarBytes(reader *bytes.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxRawFieldSize || length > uint64(reader.Len()) {
+		return nil, fmt.Errorf("invalid field length [%d]", length)
+	}
+	if length == 0 {
+		return nil, nil
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func readInt64(reader *bytes.Reader) (int64, error) {
+	var n int64
+	err := binary.Read(reader, binary.BigEndian, &n)
+	return n, err
+}
@@ -0,0 +1,119 @@
+package BLC
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestSerializeRawEncoding(t *testing.T) {
+	tx := &Transaction{
+		Vins:  []*TxInput{{[]byte{0xab, 0xcd}, 1, "alice"}},
+		Vouts: []*TxOutput{{CoinUnit, "bob", nil}, {0, "", []byte("hi")}},
+	}
+	want := "01" + // vins数量
+		"02abcd" + "0000000000000001" + "05" + hex.EncodeToString([]byte("alice")) +
+		"02" + // vouts数量
+		"0000000005f5e100" + "03" + hex.EncodeToString([]byte("bob")) + "00" +
+		"0000000000000000" + "00" + "02" + hex.EncodeToString([]byte("hi"))
+	if got := EncodeRawTransaction(tx); got != want {
+		t.Fatalf("EncodeRawTransaction =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRawTransactionRoundTrip(t *testing.T) {
+	tx, err := NewRawTransaction(
+		[]RawTxInput{{TxID: "0102", Vout: 0}, {TxID: "0304", Vout: 3}},
+		map[string]Amount{"carol": 2 * CoinUnit, "bob": CoinUnit / 2},
+	)
+	if err != nil {
+		t.Fatalf("NewRawTransaction error: %v", err)
+	}
+	// 输出按地址排序，编码与map的遍历顺序无关
+	if tx.Vouts[0].ScriptPubkey != "bob" || tx.Vouts[1].ScriptPubkey != "carol" {
+		t.Fatalf("outputs are not sorted by address: %s, %s", tx.Vouts[0].ScriptPubkey, tx.Vouts[1].ScriptPubkey)
+	}
+	if tx.IsSigned() {
+		t.Fatal("new raw transaction is signed")
+	}
+	unsignedHash := tx.TxHash
+
+	decoded, err := DecodeRawTransaction(EncodeRawTransaction(tx))
+	if err != nil {
+		t.Fatalf("DecodeRawTransaction error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, tx) {
+		t.Fatalf("decoded transaction differs:\n%+v\nwant\n%+v", decoded, tx)
+	}
+
+	if n := tx.Sign("alice"); n != 2 || !tx.IsSigned() {
+		t.Fatalf("Sign signed %d inputs, signed = %v", n, tx.IsSigned())
+	}
+	if bytes.Equal(tx.TxHash, unsignedHash) {
+		t.Fatal("signing did not change the transaction hash")
+	}
+	decoded, err = DeserializeRawTransaction(tx.SerializeRaw())
+	if err != nil {
+		t.Fatalf("DeserializeRawTransaction error: %v", err)
+	}
+	if !bytes.Equal(decoded.TxHash, tx.TxHash) || !bytes.Equal(decoded.SerializeRaw(), tx.SerializeRaw()) {
+		t.Fatal("signed transaction did not round trip")
+	}
+}
+
+func TestDeserializeRawTransactionErrors(t *testing.T) {
+	tx := &Transaction{
+		Vins:  []*TxInput{{[]byte{1}, 0, "alice"}},
+		Vouts: []*TxOutput{{CoinUnit, "bob", nil}},
+	}
+	data := tx.SerializeRaw()
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated", data[:len(data)-1]},
+		{"trailing bytes", append(append([]byte{}, data...), 0)},
+		{"no inputs", []byte{0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0}},
+		{"no outputs", []byte{1, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"huge vin count", []byte{0xff, 0xff, 0x03}},
+	}
+	for _, test := range tests {
+		if _, err := DeserializeRawTransaction(test.data); err == nil {
+			t.Errorf("%s: DeserializeRawTransaction succeeded", test.name)
+		}
+	}
+	if _, err := DecodeRawTransaction("zz"); err == nil {
+		t.Error("DecodeRawTransaction accepted invalid hex")
+	}
+}
+
+func TestReadVarBytesLimit(t *testing.T) {
+	encode := func(length int) []byte {
+		var buffer bytes.Buffer
+		writeVarBytes(&buffer, bytes.Repeat([]byte{'x'}, length))
+		return buffer.Bytes()
+	}
+
+	data, err := readVarBytes(bytes.NewReader(encode(maxRawFieldSize)))
+	if err != nil {
+		t.Fatalf("readVarBytes at the size limit error: %v", err)
+	}
+	if len(data) != maxRawFieldSize {
+		t.Fatalf("readVarBytes read %d bytes, want %d", len(data), maxRawFieldSize)
+	}
+
+	if _, err := readVarBytes(bytes.NewReader(encode(maxRawFieldSize + 1))); err == nil {
+		t.Fatal("readVarBytes accepted a field over the size limit")
+	}
+
+	// 长度大于剩余数据
+	if _, err := readVarBytes(bytes.NewReader([]byte{3, 'a', 'b'})); err == nil {
+		t.Fatal("readVarBytes accepted a truncated field")
+	}
+
+	if data, err := readVarBytes(bytes.NewReader([]byte{0})); err != nil || data != nil {
+		t.Fatalf("readVarBytes of an empty field = %v, %v", data, err)
+	}
+}
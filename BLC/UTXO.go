@@ -1,8 +1,63 @@
 package BLC
 
+import (
+	"fmt"
+	"sort"
+)
+
 // UTXO结构
 type UTXO struct {
 	TxHash []byte    // UTXO对应的交易哈希
 	Index  int       // UTXO及其所属交易的输出列表中的索引
 	Output *TxOutput // Output本身
+	Height int64     // UTXO所在区块的高度(0表示尚未打包的缓存交易)
+}
+
+// listunspent默认的确认数范围
+const (
+	defaultMinConf = 1
+	defaultMaxConf = 9999999
+)
+
+// UTXO的确认数，未打包时为0
+func (utxo *UTXO) Confirmations(tipHeight int64) int64 {
+	if utxo.Height == 0 {
+		return 0
+	}
+	return tipHeight - utxo.Height + 1
+}
+
+// UTXO排序方式
+const (
+	SortUTXOByAge   = "age"   // 按所在区块高度从低到高(最早的在前)，未确认的在最后
+	SortUTXOByValue = "value" // 按金额从大到小
+)
+
+// 按指定方式对UTXO排序，reverse为true时顺序相反
+func SortUTXOs(utxos []*UTXO, by string, reverse bool) error {
+	var less func(i, j int) bool
+	switch by {
+	case SortUTXOByAge:
+		less = func(i, j int) bool {
+			hi, hj := utxos[i].Height, utxos[j].Height
+			if hi == 0 || hj == 0 {
+				return hj == 0 && hi != 0
+			}
+			return hi < hj
+		}
+	case SortUTXOByValue:
+		less = func(i, j int) bool {
+			return utxos[i].Output.Value > utxos[j].Output.Value
+		}
+	default:
+		return fmt.Errorf("unknown sort order [%s], want %s|%s", by, SortUTXOByAge, SortUTXOByValue)
+	}
+	if reverse {
+		forward := less
+		less = func(i, j int) bool {
+			return forward(j, i)
+		}
+	}
+	sort.SliceStable(utxos, less)
+	return nil
 }
@@ -3,8 +3,10 @@ package BLC
 import (
 	"bytes"
 	"crypto/sha256"
-	"fmt"
+	"math"
 	"math/big"
+	"sync/atomic"
+	"time"
 )
 
 // 共识算法管理文件
@@ -14,6 +16,27 @@ import (
 // 目标难度值
 const targetBit = 16
 
+// 挖矿统计，用于监控指标
+var (
+	powNoncesTried  uint64 // 累计尝试的nonce数量
+	powHashRateBits uint64 // 最近一次挖矿的哈希速率(次/秒)，以float64的位表示原子存取
+)
+
+// 目标难度：平均需要尝试的哈希次数
+func Difficulty() float64 {
+	return math.Pow(2, targetBit)
+}
+
+// 累计尝试的nonce数量
+func NoncesTried() uint64 {
+	return atomic.LoadUint64(&powNoncesTried)
+}
+
+// 最近一次挖矿的哈希速率(次/秒)，尚未挖矿时为0
+func HashRate() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&powHashRateBits))
+}
+
 // 工作量证明结构
 type ProofOfWork struct {
 	// 需要共识验证的区块
@@ -36,6 +59,7 @@ func (proofOfWork *ProofOfWork) Run() ([]byte, int) {
 	var nonce = 0
 	var hashInt big.Int
 	var hash [32]byte
+	start := time.Now()
 	// 无限循环，生成符合条件的哈希值
 	for {
 		dataBytes := proofOfWork.prepareData(int64(nonce))
@@ -47,20 +71,38 @@ func (proofOfWork *ProofOfWork) Run() ([]byte, int) {
 		}
 		nonce++
 	}
-	fmt.Printf("\n碰撞次数：%d\n", nonce)
+	powLog.Infof("碰撞次数：%d\n", nonce)
+	tried := uint64(nonce) + 1
+	atomic.AddUint64(&powNoncesTried, tried)
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+		atomic.StoreUint64(&powHashRateBits, math.Float64bits(float64(tried)/elapsed))
+	}
 	return hash[:], nonce
 }
 
+// 验证区块的工作量证明：哈希与区块内容一致，并且满足目标难度
+func (pow *ProofOfWork) Validate() bool {
+	var hashInt big.Int
+	hash := sha256.Sum256(pow.prepareData(pow.Block.Nonce))
+	hashInt.SetBytes(hash[:])
+	return bytes.Equal(hash[:], pow.Block.Hash) && pow.target.Cmp(&hashInt) == 1
+}
+
 // 生成准备数据
 func (pow *ProofOfWork) prepareData(nonce int64) []byte {
+	return powData(pow.Block.TimeStamp, pow.Block.Height, pow.Block.PrevBlockHash, pow.Block.HashTransaction(), nonce)
+}
+
+// 工作量证明的哈希输入，区块与区块头共用
+func powData(timeStamp, height int64, prevBlockHash, txRoot []byte, nonce int64) []byte {
 	var data []byte
-	timeStampBytes := IntoHex(pow.Block.TimeStamp)
-	heightByte := IntoHex(pow.Block.Height)
+	timeStampBytes := IntoHex(timeStamp)
+	heightByte := IntoHex(height)
 	data = bytes.Join([][]byte{
 		timeStampBytes,
 		heightByte,
-		pow.Block.PrevBlockHash,
-		pow.Block.HashTransaction(),
+		prevBlockHash,
+		txRoot,
 		IntoHex(targetBit),
 		IntoHex(nonce),
 	}, []byte{})
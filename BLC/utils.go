@@ -3,9 +3,11 @@ package BLC
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
-	"log"
+	"fmt"
 	"os"
+	"strings"
 )
 
 //实现int64转成[]byte
@@ -13,7 +15,7 @@ func IntoHex(data int64) []byte {
 	buffer := new(bytes.Buffer)
 	err := binary.Write(buffer, binary.BigEndian, data)
 	if err != nil {
-		log.Panicf("int transact to []byte failed! %v\n", err)
+		cliLog.Panicf("int transact to []byte failed! %v\n", err)
 	}
 	return buffer.Bytes()
 }
@@ -23,7 +25,7 @@ func JSONToSlice(jsonString string) []string {
 	var strSlice []string
 	// json
 	if err := json.Unmarshal([]byte(jsonString), &strSlice); nil != err {
-		log.Panicf("json to []string failed! %v\n", err)
+		cliLog.Panicf("json to []string failed! %v\n", err)
 	}
 	return strSlice
 }
@@ -36,3 +38,45 @@ func IsValidArgs() {
 		os.Exit(1)
 	}
 }
+
+// 标准JSON对象转地址-金额映射
+// 例如：{"Alice":3,"Bob":1.25}
+func JSONToOutputs(jsonString string) map[string]Amount {
+	outputs := make(map[string]Amount)
+	if err := json.Unmarshal([]byte(jsonString), &outputs); nil != err {
+		cliLog.Panicf("json to map[string]Amount failed! %v\n", err)
+	}
+	return outputs
+}
+
+// 判断参数是否为JSON对象
+func IsJSONObject(jsonString string) bool {
+	return strings.HasPrefix(strings.TrimSpace(jsonString), "{")
+}
+
+// 标准JSON格式转原始交易输入列表
+// 例如：[{"txid":"ab12...","vout":0}]
+func JSONToRawInputs(jsonString string) []RawTxInput {
+	var inputs []RawTxInput
+	if err := json.Unmarshal([]byte(jsonString), &inputs); nil != err {
+		cliLog.Panicf("json to []RawTxInput failed! %v\n", err)
+	}
+	return inputs
+}
+
+// 解析附加数据参数：以0x开头按hex解析，否则按文本处理
+func ParseDataArg(data string) ([]byte, error) {
+	var result []byte
+	if strings.HasPrefix(data, "0x") {
+		var err error
+		if result, err = hex.DecodeString(data[2:]); err != nil {
+			return nil, err
+		}
+	} else {
+		result = []byte(data)
+	}
+	if len(result) > MaxDataOutputSize {
+		return nil, fmt.Errorf("data size [%d] exceeds [%d]", len(result), MaxDataOutputSize)
+	}
+	return result, nil
+}
@@ -0,0 +1,230 @@
+package harness
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"bitcoin/BLC"
+)
+
+// 多节点测试环境管理文件
+// 在一个进程内启动多个节点，每个节点使用临时目录中独立的数据库，节点之间通过模拟网络连接
+// 所有节点共享同一个创世区块，创世区块的奖励属于MinerAddress，用于各节点生成交易并挖矿
+//
+// 用法示例：
+//   h, _ := harness.New(4)
+//   defer h.Close()
+//   h.Connect(0, 1); h.Connect(1, 2); h.Connect(2, 3)
+//   h.Partition([]int{0, 1}, []int{2, 3})
+//   h.Mine(0, 1); h.Mine(2, 3)
+//   h.Heal()
+//   h.WaitForSameTip(10*time.Second)
+
+// 创世区块奖励的接收地址
+const MinerAddress = "miner"
+
+// 每个区块中转账交易的金额
+var blockTxAmount = BLC.Amount(BLC.CoinUnit / 1000)
+
+// 等待时轮询的间隔
+const pollInterval = 20 * time.Millisecond
+
+// 多节点测试环境
+type Harness struct {
+	Network *Network
+	Nodes   []*BLC.Node
+
+	dir         string
+	blockchains []*BLC.BlockChain
+	links       [][2]int // 通过Connect建立的连接，恢复分区后重新连接
+}
+
+// 节点的监听地址
+func NodeAddr(index int) string {
+	return fmt.Sprintf("node%d:8333", index)
+}
+
+// 创建并启动count个节点，节点之间尚未连接
+func New(count int) (*Harness, error) {
+	dir, err := ioutil.TempDir("", "harness")
+	if err != nil {
+		return nil, err
+	}
+	h := &Harness{Network: NewNetwork(), dir: dir}
+	genesis := BLC.CreateGenesisBlock([]*BLC.Transaction{BLC.NewCoinbaseTransaction(MinerAddress)})
+	for index := 0; index < count; index++ {
+		blockchain, err := BLC.OpenBlockChain(filepath.Join(dir, fmt.Sprintf("node%d.db", index)))
+		if err != nil {
+			h.Close()
+			return nil, err
+		}
+		h.blockchains = append(h.blockchains, blockchain)
+		if _, err := blockchain.AcceptBlock(genesis); err != nil {
+			h.Close()
+			return nil, fmt.Errorf("accept genesis block failed: %v", err)
+		}
+		node := BLC.NewNode(NodeAddr(index), blockchain)
+		node.SetTransport(h.Network.Transport(NodeAddr(index)))
+		// 连接关系由测试指定，不自动连接其他节点
+		node.SetTargetOutbound(0)
+		if err := node.Start(); err != nil {
+			h.Close()
+			return nil, err
+		}
+		h.Nodes = append(h.Nodes, node)
+	}
+	return h, nil
+}
+
+// 停止所有节点并删除临时目录
+func (h *Harness) Close() {
+	for _, node := range h.Nodes {
+		node.Stop()
+	}
+	for _, blockchain := range h.blockchains {
+		blockchain.DB.Close()
+	}
+	os.RemoveAll(h.dir)
+}
+
+// 节点from主动连接节点to，并等待双方完成握手
+func (h *Harness) Connect(from, to int) error {
+	h.links = append(h.links, [2]int{from, to})
+	return h.connect(from, to)
+}
+
+func (h *Harness) connect(from, to int) error {
+	if err := h.Nodes[from].Connect(NodeAddr(to)); err != nil {
+		return err
+	}
+	return h.waitFor(5*time.Second, func() bool {
+		return h.connected(from, to) && h.connected(to, from)
+	}, fmt.Sprintf("handshake between node %d and node %d", from, to))
+}
+
+// 节点a是否已与节点b完成握手
+func (h *Harness) connected(a, b int) bool {
+	for _, peer := range h.Nodes[a].HandshakedPeers() {
+		if peer.ListenAddr() == NodeAddr(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// 划分网络分区，每组为节点编号列表
+func (h *Harness) Partition(groups ...[]int) {
+	var addrGroups [][]string
+	for _, group := range groups {
+		var addrs []string
+		for _, index := range group {
+			addrs = append(addrs, NodeAddr(index))
+		}
+		addrGroups = append(addrGroups, addrs)
+	}
+	h.Network.Partition(addrGroups...)
+}
+
+// 恢复网络分区，并重新建立断开的连接
+func (h *Harness) Heal() error {
+	h.Network.Heal()
+	for _, link := range h.links {
+		if h.connected(link[0], link[1]) {
+			continue
+		}
+		if err := h.connect(link[0], link[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 在指定节点上挖count个区块，每个区块包含一笔从MinerAddress转出的交易
+func (h *Harness) Mine(index, count int) ([]*BLC.Block, error) {
+	node := h.Nodes[index]
+	var blocks []*BLC.Block
+	for i := 0; i < count; i++ {
+		to := fmt.Sprintf("node%d-%d", index, i)
+		if _, err := node.SendToAddress(MinerAddress, to, blockTxAmount); err != nil {
+			return blocks, fmt.Errorf("node %d create transaction failed: %v", index, err)
+		}
+		block := node.MineMempool()
+		if block == nil {
+			return blocks, fmt.Errorf("node %d mined no block", index)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// 节点的最新区块哈希与高度
+func (h *Harness) BestBlock(index int) ([]byte, int64) {
+	return h.Nodes[index].BestBlock()
+}
+
+// 等待指定节点(未指定时为所有节点)的高度达到height
+func (h *Harness) WaitForHeight(height int64, timeout time.Duration, indexes ...int) error {
+	indexes = h.indexes(indexes)
+	return h.waitFor(timeout, func() bool {
+		for _, index := range indexes {
+			if _, best := h.BestBlock(index); best < height {
+				return false
+			}
+		}
+		return true
+	}, fmt.Sprintf("height %d", height))
+}
+
+// 等待指定节点(未指定时为所有节点)的最新区块相同
+func (h *Harness) WaitForSameTip(timeout time.Duration, indexes ...int) error {
+	indexes = h.indexes(indexes)
+	return h.waitFor(timeout, func() bool {
+		first, _ := h.BestBlock(indexes[0])
+		for _, index := range indexes[1:] {
+			if tip, _ := h.BestBlock(index); !bytes.Equal(tip, first) {
+				return false
+			}
+		}
+		return true
+	}, "same tip")
+}
+
+// 等待指定节点的最新区块为hash
+func (h *Harness) WaitForTip(hash []byte, timeout time.Duration, indexes ...int) error {
+	indexes = h.indexes(indexes)
+	return h.waitFor(timeout, func() bool {
+		for _, index := range indexes {
+			if tip, _ := h.BestBlock(index); !bytes.Equal(tip, hash) {
+				return false
+			}
+		}
+		return true
+	}, fmt.Sprintf("tip %x", hash))
+}
+
+func (h *Harness) indexes(indexes []int) []int {
+	if len(indexes) > 0 {
+		return indexes
+	}
+	for index := range h.Nodes {
+		indexes = append(indexes, index)
+	}
+	return indexes
+}
+
+// 等待条件成立，超时返回错误
+func (h *Harness) waitFor(timeout time.Duration, condition func() bool, what string) error {
+	deadline := time.Now().Add(timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			return errors.New("timeout waiting for " + what)
+		}
+		time.Sleep(pollInterval)
+	}
+	return nil
+}
@@ -0,0 +1,393 @@
+package harness
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"bitcoin/BLC"
+)
+
+// 模拟网络管理文件
+// 在进程内模拟节点之间的连接，支持延迟、丢包与网络分区：
+//   延迟：每次写入的数据在指定时间后才能被对方读到，保持写入顺序
+//   丢包：节点每条消息只调用一次Write，按比例丢弃整次写入即丢弃整条消息
+//   分区：不同分区的节点之间的连接被断开，且无法建立新连接，恢复后才能重新连接
+
+// 网络不可达
+var ErrUnreachable = errors.New("network unreachable")
+
+// 模拟网络
+type Network struct {
+	mutex     sync.Mutex
+	listeners map[string]*listener
+	conns     map[*conn]bool
+	groups    map[string]int // 节点地址->分区编号，未分区时为空
+	latency   time.Duration
+	lossRate  float64
+	random    *rand.Rand
+	nextPort  int
+}
+
+// 创建模拟网络
+func NewNetwork() *Network {
+	return &Network{
+		listeners: make(map[string]*listener),
+		conns:     make(map[*conn]bool),
+		groups:    make(map[string]int),
+		random:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		nextPort:  40000,
+	}
+}
+
+// 设置单向延迟
+func (network *Network) SetLatency(latency time.Duration) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	network.latency = latency
+}
+
+// 设置丢包率(0~1)
+func (network *Network) SetLossRate(rate float64) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	network.lossRate = rate
+}
+
+// 划分网络分区：每组节点地址为一个分区，未列出的节点属于单独的默认分区
+// 断开所有跨分区的连接
+func (network *Network) Partition(groups ...[]string) {
+	network.mutex.Lock()
+	network.groups = make(map[string]int)
+	for index, group := range groups {
+		for _, addr := range group {
+			network.groups[addr] = index + 1
+		}
+	}
+	var cut []*conn
+	for c := range network.conns {
+		if !network.reachable(c.localNode, c.remoteNode) {
+			cut = append(cut, c)
+		}
+	}
+	network.mutex.Unlock()
+
+	for _, c := range cut {
+		c.Close()
+	}
+}
+
+// 恢复网络分区
+func (network *Network) Heal() {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	network.groups = make(map[string]int)
+}
+
+// 两个节点之间是否可达
+// 调用时需持有network.mutex
+func (network *Network) reachable(from, to string) bool {
+	return network.groups[from] == network.groups[to]
+}
+
+// 是否丢弃本次写入
+func (network *Network) drop() bool {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	return network.lossRate > 0 && network.random.Float64() < network.lossRate
+}
+
+// 数据的送达时间
+func (network *Network) deliverTime() time.Time {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	return time.Now().Add(network.latency)
+}
+
+// 绑定到指定节点地址的传输，用于判断连接属于哪个分区
+func (network *Network) Transport(nodeAddr string) BLC.Transport {
+	return &transport{network, nodeAddr}
+}
+
+// 模拟网络中的传输
+type transport struct {
+	network  *Network
+	nodeAddr string
+}
+
+func (t *transport) Listen(addr string) (net.Listener, error) {
+	network := t.network
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	if _, ok := network.listeners[addr]; ok {
+		return nil, fmt.Errorf("address [%s] already in use", addr)
+	}
+	l := &listener{
+		network: network,
+		addr:    simAddr(addr),
+		accept:  make(chan net.Conn, 16),
+		closed:  make(chan struct{}),
+	}
+	network.listeners[addr] = l
+	return l, nil
+}
+
+func (t *transport) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	network := t.network
+	network.mutex.Lock()
+	l, ok := network.listeners[addr]
+	if !ok {
+		network.mutex.Unlock()
+		return nil, fmt.Errorf("dial [%s]: connection refused", addr)
+	}
+	if !network.reachable(t.nodeAddr, addr) {
+		network.mutex.Unlock()
+		return nil, fmt.Errorf("dial [%s]: %v", addr, ErrUnreachable)
+	}
+	// 对方看到的地址：本节点的主机加临时端口
+	host, _, err := net.SplitHostPort(t.nodeAddr)
+	if err != nil {
+		host = t.nodeAddr
+	}
+	network.nextPort++
+	clientAddr := simAddr(net.JoinHostPort(host, fmt.Sprint(network.nextPort)))
+	toServer, toClient := newPipe(), newPipe()
+	client := newConn(network, t.nodeAddr, addr, clientAddr, simAddr(addr), toClient, toServer)
+	server := newConn(network, addr, t.nodeAddr, simAddr(addr), clientAddr, toServer, toClient)
+	network.conns[client] = true
+	network.conns[server] = true
+	network.mutex.Unlock()
+
+	select {
+	case l.accept <- server:
+		return client, nil
+	case <-l.closed:
+	case <-time.After(timeout):
+	}
+	client.Close()
+	return nil, fmt.Errorf("dial [%s]: connection refused", addr)
+}
+
+// 模拟地址
+type simAddr string
+
+func (addr simAddr) Network() string { return "sim" }
+func (addr simAddr) String() string  { return string(addr) }
+
+// 模拟监听
+type listener struct {
+	network   *Network
+	addr      net.Addr
+	accept    chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.accept:
+		return c, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *listener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closed)
+		l.network.mutex.Lock()
+		delete(l.network.listeners, l.addr.String())
+		l.network.mutex.Unlock()
+	})
+	return nil
+}
+
+func (l *listener) Addr() net.Addr {
+	return l.addr
+}
+
+// 单向数据通道
+type pipe struct {
+	mutex   sync.Mutex
+	packets []packet // 尚未读取的数据，按写入顺序排列
+	pending []byte   // 已取出但未读完的数据
+	eof     bool     // 写入方已关闭
+	notify  chan struct{}
+}
+
+// 一次写入的数据
+type packet struct {
+	data []byte
+	at   time.Time // 送达时间
+}
+
+func newPipe() *pipe {
+	return &pipe{notify: make(chan struct{}, 1)}
+}
+
+func (p *pipe) signal() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (p *pipe) write(data []byte, at time.Time) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.eof {
+		return net.ErrClosed
+	}
+	// 送达时间不早于前一次写入，保持顺序
+	if n := len(p.packets); n > 0 && at.Before(p.packets[n-1].at) {
+		at = p.packets[n-1].at
+	}
+	p.packets = append(p.packets, packet{append([]byte{}, data...), at})
+	p.signal()
+	return nil
+}
+
+func (p *pipe) closeWrite() {
+	p.mutex.Lock()
+	p.eof = true
+	p.mutex.Unlock()
+	p.signal()
+}
+
+// 模拟连接
+type conn struct {
+	network    *Network
+	localNode  string // 本端节点的监听地址
+	remoteNode string // 对端节点的监听地址
+	localAddr  net.Addr
+	remoteAddr net.Addr
+	in         *pipe // 读取的数据
+	out        *pipe // 写入的数据
+
+	mutex        sync.Mutex
+	readDeadline time.Time
+	deadlineSet  chan struct{}
+	closed       chan struct{}
+	closeOnce    sync.Once
+}
+
+func newConn(network *Network, localNode, remoteNode string, localAddr, remoteAddr net.Addr, in, out *pipe) *conn {
+	return &conn{
+		network:     network,
+		localNode:   localNode,
+		remoteNode:  remoteNode,
+		localAddr:   localAddr,
+		remoteAddr:  remoteAddr,
+		in:          in,
+		out:         out,
+		deadlineSet: make(chan struct{}, 1),
+		closed:      make(chan struct{}),
+	}
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	for {
+		select {
+		case <-c.closed:
+			return 0, net.ErrClosed
+		default:
+		}
+		c.in.mutex.Lock()
+		if len(c.in.pending) > 0 {
+			n := copy(b, c.in.pending)
+			c.in.pending = c.in.pending[n:]
+			c.in.mutex.Unlock()
+			return n, nil
+		}
+		var wait <-chan time.Time
+		if len(c.in.packets) > 0 {
+			if d := time.Until(c.in.packets[0].at); d <= 0 {
+				c.in.pending = c.in.packets[0].data
+				c.in.packets = c.in.packets[1:]
+				c.in.mutex.Unlock()
+				continue
+			} else {
+				wait = time.After(d)
+			}
+		} else if c.in.eof {
+			c.in.mutex.Unlock()
+			return 0, io.EOF
+		}
+		c.in.mutex.Unlock()
+
+		c.mutex.Lock()
+		deadline := c.readDeadline
+		c.mutex.Unlock()
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				return 0, os.ErrDeadlineExceeded
+			}
+			timeout = time.After(d)
+		}
+		select {
+		case <-c.in.notify:
+		case <-wait:
+		case <-timeout:
+		case <-c.deadlineSet:
+		case <-c.closed:
+		}
+	}
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	if c.network.drop() {
+		return len(b), nil
+	}
+	if err := c.out.write(b, c.network.deliverTime()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// 关闭连接：对端读完已写入的数据后读到EOF
+func (c *conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.out.closeWrite()
+		c.in.closeWrite()
+		c.network.mutex.Lock()
+		delete(c.network.conns, c)
+		c.network.mutex.Unlock()
+	})
+	return nil
+}
+
+func (c *conn) LocalAddr() net.Addr  { return c.localAddr }
+func (c *conn) RemoteAddr() net.Addr { return c.remoteAddr }
+
+func (c *conn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error {
+	c.mutex.Lock()
+	c.readDeadline = t
+	c.mutex.Unlock()
+	select {
+	case c.deadlineSet <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// 写入不会阻塞，忽略写超时
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,63 @@
+package harness
+
+import (
+	"fmt"
+	"time"
+)
+
+// 常用场景文件
+// 组合Harness的操作，验证多节点之间的共识行为，出错时返回错误
+
+// 分区后两侧分别挖矿，恢复后所有节点切换到更长的链
+// nodes:节点数量(至少2个)，分为前后两半；light/heavy:两侧挖出的区块数
+func PartitionReorg(nodes, light, heavy int) error {
+	if nodes < 2 || light >= heavy {
+		return fmt.Errorf("invalid scenario: nodes [%d] light [%d] heavy [%d]", nodes, light, heavy)
+	}
+	h, err := New(nodes)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	// 链式连接所有节点
+	for index := 0; index+1 < nodes; index++ {
+		if err := h.Connect(index, index+1); err != nil {
+			return err
+		}
+	}
+	if _, err := h.Mine(0, 1); err != nil {
+		return err
+	}
+	if err := h.WaitForSameTip(10 * time.Second); err != nil {
+		return err
+	}
+
+	var left, right []int
+	for index := 0; index < nodes; index++ {
+		if index < nodes/2 {
+			left = append(left, index)
+		} else {
+			right = append(right, index)
+		}
+	}
+	h.Partition(left, right)
+	if _, err := h.Mine(left[0], light); err != nil {
+		return err
+	}
+	heavyBlocks, err := h.Mine(right[0], heavy)
+	if err != nil {
+		return err
+	}
+	if err := h.WaitForSameTip(10*time.Second, left...); err != nil {
+		return err
+	}
+	if err := h.WaitForSameTip(10*time.Second, right...); err != nil {
+		return err
+	}
+
+	if err := h.Heal(); err != nil {
+		return err
+	}
+	return h.WaitForTip(heavyBlocks[len(heavyBlocks)-1].Hash, 30*time.Second)
+}
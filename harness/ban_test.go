@@ -0,0 +1,126 @@
+package harness
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"bitcoin/BLC"
+)
+
+// 从transport连接节点，以listenAddr为监听地址完成version交换
+// 返回nil表示连接被拒绝
+func dialWithVersion(t *testing.T, transport BLC.Transport, addr, listenAddr string, nonce uint64) net.Conn {
+	t.Helper()
+	conn, err := transport.Dial(addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := &BLC.VersionMsg{Version: 1, AddrFrom: listenAddr, Nonce: nonce}
+	if err := BLC.WriteMessage(conn, "version", version.Serialize()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BLC.ReadMessage(conn); err != nil {
+		conn.Close()
+		return nil
+	}
+	return conn
+}
+
+// 发送声明超长负载的消息头，违规分数直接达到封禁阈值
+func sendOversizedHeader(t *testing.T, conn net.Conn) {
+	t.Helper()
+	header := make([]byte, 4+12+4+4)
+	binary.BigEndian.PutUint32(header, 0xb1c0de01)
+	copy(header[4:], "block")
+	binary.BigEndian.PutUint32(header[16:], 1<<31)
+	if _, err := conn.Write(header); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 冒用其他节点监听地址的违规节点只封禁自己的连接地址，不影响被冒用的节点
+func TestForgedAddrFromDoesNotBanThirdNode(t *testing.T) {
+	h, err := New(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	conn := dialWithVersion(t, h.Network.Transport("evil:8333"), NodeAddr(0), NodeAddr(2), 42)
+	if conn == nil {
+		t.Fatal("connection of the evil peer was refused")
+	}
+	defer conn.Close()
+	sendOversizedHeader(t, conn)
+
+	bans := h.Nodes[0].BanManager()
+	err = h.waitFor(5*time.Second, func() bool { return len(bans.List()) > 0 }, "ban of the misbehaving peer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := bans.List()
+	if len(list) != 1 || list[0].Addr != "evil" {
+		t.Fatalf("ban list = %+v, want only the observed host [evil]", list)
+	}
+	if bans.IsBanned(NodeAddr(2)) {
+		t.Fatalf("node [%s] is banned by a forged version message", NodeAddr(2))
+	}
+	if err := h.Connect(2, 0); err != nil {
+		t.Fatalf("node 2 cannot connect to node 0: %v", err)
+	}
+	if !bans.IsBanned("evil:40123") {
+		t.Fatalf("other connections from the banned host are not rejected")
+	}
+}
+
+// 本机节点从临时端口发起连接，违规后按其声明的回环监听地址封禁，换端口重新连接仍被拒绝
+func TestBannedLoopbackPeerRedialRefused(t *testing.T) {
+	h, err := New(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	const evilAddr = "127.0.0.1:3001"
+	transport := h.Network.Transport(evilAddr)
+	conn := dialWithVersion(t, transport, NodeAddr(0), evilAddr, 42)
+	if conn == nil {
+		t.Fatal("connection of the evil peer was refused")
+	}
+	defer conn.Close()
+	firstAddr := conn.LocalAddr().String()
+	sendOversizedHeader(t, conn)
+
+	bans := h.Nodes[0].BanManager()
+	err = h.waitFor(5*time.Second, func() bool { return len(bans.List()) > 0 }, "ban of the misbehaving peer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := bans.List(); len(list) != 1 || list[0].Addr != evilAddr {
+		t.Fatalf("ban list = %+v, want only the declared listen address [%s]", list, evilAddr)
+	}
+
+	// 重新连接使用新的临时端口
+	redial, err := transport.Dial(NodeAddr(0), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redialAddr := redial.LocalAddr().String()
+	redial.Close()
+	if redialAddr == firstAddr {
+		t.Fatalf("redial reused the source address [%s]", firstAddr)
+	}
+	if conn := dialWithVersion(t, transport, NodeAddr(0), evilAddr, 43); conn != nil {
+		conn.Close()
+		t.Fatal("banned loopback peer reconnected from a new source port")
+	}
+
+	// 其他本机节点不受影响
+	conn = dialWithVersion(t, h.Network.Transport("127.0.0.1:3002"), NodeAddr(0), "127.0.0.1:3002", 44)
+	if conn == nil {
+		t.Fatal("another loopback peer was refused")
+	}
+	conn.Close()
+}
@@ -0,0 +1,218 @@
+package harness
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"bitcoin/BLC"
+)
+
+// 检查所有节点的最新区块为tip，交易池中恰好是txs
+func checkNodes(t *testing.T, h *Harness, tip []byte, txs ...*BLC.Transaction) {
+	t.Helper()
+	for index, node := range h.Nodes {
+		if best, height := h.BestBlock(index); !bytes.Equal(best, tip) {
+			t.Errorf("node %d tip = %x (height %d), want %x", index, best, height, tip)
+		}
+		mempool := node.Mempool()
+		if mempool.Count() != len(txs) {
+			t.Errorf("node %d mempool has %d transactions, want %d", index, mempool.Count(), len(txs))
+		}
+		for _, tx := range txs {
+			if !mempool.Has(tx.TxHash) {
+				t.Errorf("node %d mempool misses transaction %x", index, tx.TxHash)
+			}
+		}
+	}
+}
+
+// 等待所有节点的交易池中有count笔交易
+func waitForMempool(h *Harness, count int, timeout time.Duration) error {
+	return h.waitFor(timeout, func() bool {
+		for _, node := range h.Nodes {
+			if node.Mempool().Count() != count {
+				return false
+			}
+		}
+		return true
+	}, "mempool sync")
+}
+
+// 链式连接所有节点
+func newChain(t *testing.T, count int) *Harness {
+	t.Helper()
+	h, err := New(count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index := 0; index+1 < count; index++ {
+		if err := h.Connect(index, index+1); err != nil {
+			h.Close()
+			t.Fatal(err)
+		}
+	}
+	return h
+}
+
+// 分区两侧分别挖矿，恢复后所有节点切换到较长的链
+func TestPartitionReorg(t *testing.T) {
+	h := newChain(t, 4)
+	defer h.Close()
+	if _, err := h.Mine(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.WaitForSameTip(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Partition([]int{0, 1}, []int{2, 3})
+	light, err := h.Mine(0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	heavy, err := h.Mine(2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lightTip, heavyTip := light[len(light)-1].Hash, heavy[len(heavy)-1].Hash
+	if err := h.WaitForTip(lightTip, 10*time.Second, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.WaitForTip(heavyTip, 10*time.Second, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.Heal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.WaitForTip(heavyTip, 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// 较短的链上的交易与较长的链上的交易花费相同的输出，切换后不应留在交易池中
+	if err := waitForMempool(h, 0, 5*time.Second); err != nil {
+		t.Error(err)
+	}
+	checkNodes(t, h, heavyTip)
+	if _, height := h.BestBlock(0); height != 5 {
+		t.Errorf("height = %d, want 5", height)
+	}
+}
+
+// 丢包的网络中区块最终传播到所有节点
+// 丢失的请求要等待同步管理器的请求超时后重发，耗时较长
+func TestLossyLink(t *testing.T) {
+	if testing.Short() {
+		t.Skip("lossy link recovery waits for request timeouts")
+	}
+	h := newChain(t, 3)
+	defer h.Close()
+	h.Network.SetLossRate(0.3)
+	blocks, err := h.Mine(0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 恢复后挖出的区块触发落后的节点同步缺失的区块
+	h.Network.SetLossRate(0)
+	last, err := h.Mine(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks = append(blocks, last...)
+	tip := blocks[len(blocks)-1].Hash
+	if err := h.WaitForTip(tip, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForMempool(h, 0, 5*time.Second); err != nil {
+		t.Error(err)
+	}
+	checkNodes(t, h, tip)
+}
+
+// 高延迟的网络中区块与交易传播到所有节点
+func TestHighLatencyLink(t *testing.T) {
+	h := newChain(t, 3)
+	defer h.Close()
+	h.Network.SetLatency(200 * time.Millisecond)
+	blocks, err := h.Mine(0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tip := blocks[len(blocks)-1].Hash
+	if err := h.WaitForTip(tip, 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// 未打包的交易经过两跳传播到最远的节点
+	tx, err := h.Nodes[0].SendToAddress(MinerAddress, "pending", blockTxAmount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForMempool(h, 1, 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	checkNodes(t, h, tip, tx)
+}
+
+// 切换到较长的链后，较短的链上与新链不冲突的交易放回交易池，冲突的交易被丢弃
+func TestReorgRestoresMempool(t *testing.T) {
+	h := newChain(t, 4)
+	defer h.Close()
+	// 共同的区块中给alice转账，之后alice的交易与MinerAddress的交易互不冲突
+	if _, err := h.Nodes[0].SendToAddress(MinerAddress, "alice", 10*blockTxAmount); err != nil {
+		t.Fatal(err)
+	}
+	if h.Nodes[0].MineMempool() == nil {
+		t.Fatal("node 0 mined no block")
+	}
+	if err := h.WaitForSameTip(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Partition([]int{0, 1}, []int{2, 3})
+	aliceTx, err := h.Nodes[0].SendToAddress("alice", "bob", blockTxAmount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	light, err := h.Mine(0, 1) // 包含aliceTx与一笔MinerAddress的交易
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(light[0].Txs) != 2 {
+		t.Fatalf("light block has %d transactions, want 2", len(light[0].Txs))
+	}
+	heavy, err := h.Mine(2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	heavyTip := heavy[len(heavy)-1].Hash
+	if err := h.WaitForTip(light[0].Hash, 10*time.Second, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.WaitForTip(heavyTip, 10*time.Second, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.Heal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.WaitForTip(heavyTip, 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForMempool(h, 1, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	checkNodes(t, h, heavyTip, aliceTx)
+
+	// 放回的交易可以被打包
+	block := h.Nodes[3].MineMempool()
+	if block == nil {
+		t.Fatal("node 3 mined no block")
+	}
+	if err := h.WaitForTip(block.Hash, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForMempool(h, 0, 5*time.Second); err != nil {
+		t.Error(err)
+	}
+	checkNodes(t, h, block.Hash)
+}
@@ -0,0 +1,187 @@
+package harness
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"bitcoin/BLC"
+)
+
+// 调用节点的RPC方法，将结果解析到result中
+func callRPC(t *testing.T, server *BLC.RPCServer, result interface{}, method string, params ...interface{}) error {
+	t.Helper()
+	if params == nil {
+		params = []interface{}{}
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := server.Call(method, raw)
+	if err != nil {
+		return err
+	}
+	if result != nil {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return nil
+}
+
+// 通过RPC修改运行中的节点的封禁列表，封禁立即断开已有的连接
+func TestBanRPC(t *testing.T) {
+	h, err := New(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if err := h.Connect(1, 0); err != nil {
+		t.Fatal(err)
+	}
+	server := BLC.NewRPCServer(h.Nodes[0], BLC.RPCConfig{})
+
+	var status BLC.BanStatusResult
+	if err := callRPC(t, server, &status, "setban", "node1", "add", 3600); err != nil {
+		t.Fatalf("setban add error: %v", err)
+	}
+	if !status.Banned || status.Address != "node1" {
+		t.Fatalf("setban add = %+v", status)
+	}
+	err = h.waitFor(5*time.Second, func() bool { return len(h.Nodes[0].Peers()) == 0 }, "disconnect of the banned peer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn := dialWithVersion(t, h.Network.Transport(NodeAddr(1)), NodeAddr(0), NodeAddr(1), 42); conn != nil {
+		conn.Close()
+		t.Fatal("banned node reconnected")
+	}
+
+	var list []*BLC.BanResult
+	if err := callRPC(t, server, &list, "listbanned"); err != nil {
+		t.Fatalf("listbanned error: %v", err)
+	}
+	if len(list) != 1 || list[0].Address != "node1" || list[0].Until-list[0].Created != 3600 {
+		t.Fatalf("listbanned = %+v", list)
+	}
+
+	if err := callRPC(t, server, nil, "setban", "node1", "remove"); err != nil {
+		t.Fatalf("setban remove error: %v", err)
+	}
+	if err := callRPC(t, server, nil, "setban", "node1", "remove"); err == nil {
+		t.Fatal("removing an address that is not banned succeeded")
+	}
+	if err := h.Connect(1, 0); err != nil {
+		t.Fatalf("unbanned node cannot connect: %v", err)
+	}
+
+	for _, params := range [][]interface{}{{"node2", "ban"}, {"", "add"}, {"node2", "add", 0}} {
+		if err := callRPC(t, server, nil, "setban", params...); err == nil {
+			t.Errorf("setban %v succeeded", params)
+		}
+	}
+	if err := callRPC(t, server, nil, "setban", "node2", "add"); err != nil {
+		t.Fatalf("setban with the default bantime error: %v", err)
+	}
+	if err := callRPC(t, server, nil, "clearbanned"); err != nil {
+		t.Fatalf("clearbanned error: %v", err)
+	}
+	if list := h.Nodes[0].BanManager().List(); len(list) != 0 {
+		t.Fatalf("ban list after clearbanned = %+v", list)
+	}
+}
+
+// RPC错误码，非RPC错误时返回0
+func rpcErrorCode(err error) int {
+	if rpcErr, ok := err.(*BLC.RPCError); ok {
+		return rpcErr.Code
+	}
+	return 0
+}
+
+// 通过RPC发起一对多转账(附加数据、指定选币策略)与提交原始交易，交易进入交易池并转发给其他节点
+func TestSendRPC(t *testing.T) {
+	h, err := New(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if err := h.Connect(1, 0); err != nil {
+		t.Fatal(err)
+	}
+	server := BLC.NewRPCServer(h.Nodes[0], BLC.RPCConfig{})
+
+	amounts := map[string]BLC.Amount{"alice": blockTxAmount, "bob": 2 * blockTxAmount}
+	var txid string
+	if err := callRPC(t, server, &txid, "sendmany", MinerAddress, amounts, hex.EncodeToString([]byte("memo")), "smallest"); err != nil {
+		t.Fatalf("sendmany error: %v", err)
+	}
+	txHash, _ := hex.DecodeString(txid)
+	tx := h.Nodes[0].Mempool().Get(txHash)
+	if tx == nil {
+		t.Fatalf("transaction %s is not in the mempool", txid)
+	}
+	values := make(map[string]BLC.Amount)
+	var data []byte
+	for _, out := range tx.Vouts {
+		if out.IsDataOutput() {
+			data = out.Data
+			continue
+		}
+		values[out.ScriptPubkey] = out.Value
+	}
+	if values["alice"] != blockTxAmount || values["bob"] != 2*blockTxAmount || string(data) != "memo" {
+		t.Fatalf("sendmany outputs = %v, data = %q", values, data)
+	}
+
+	invalid := [][]interface{}{
+		{MinerAddress, map[string]BLC.Amount{}},
+		{MinerAddress, map[string]BLC.Amount{"alice": 0}},
+		{MinerAddress, amounts, "zz"},
+		{MinerAddress, amounts, hex.EncodeToString(make([]byte, BLC.MaxDataOutputSize+1))},
+		{MinerAddress, amounts, "", "random"},
+	}
+	for _, params := range invalid {
+		if err := callRPC(t, server, nil, "sendmany", params...); rpcErrorCode(err) != -8 {
+			t.Errorf("sendmany %v error = %v, want invalid parameter", params, err)
+		}
+	}
+	err = callRPC(t, server, nil, "sendmany", "alice", map[string]BLC.Amount{"bob": 2 * blockTxAmount})
+	if rpcErrorCode(err) != -6 {
+		t.Errorf("sendmany with insufficient funds error = %v, want insufficient funds", err)
+	}
+
+	// 花费交易池中alice收到的输出
+	raw, err := BLC.NewRawTransaction([]BLC.RawTxInput{{TxID: txid, Vout: 0}}, map[string]BLC.Amount{"carol": blockTxAmount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw.Sign("alice")
+	rawHex := BLC.EncodeRawTransaction(raw)
+	var rawTxID string
+	if err := callRPC(t, server, &rawTxID, "sendrawtransaction", rawHex); err != nil {
+		t.Fatalf("sendrawtransaction error: %v", err)
+	}
+	if rawTxID != hex.EncodeToString(raw.TxHash) {
+		t.Fatalf("sendrawtransaction = %s, want %x", rawTxID, raw.TxHash)
+	}
+	if err := callRPC(t, server, nil, "sendrawtransaction", rawHex); rpcErrorCode(err) != -26 {
+		t.Errorf("resubmitting the raw transaction error = %v, want verify rejected", err)
+	}
+	if err := callRPC(t, server, nil, "sendrawtransaction", "zz"); rpcErrorCode(err) != -22 {
+		t.Errorf("sendrawtransaction with malformed hex error = %v, want deserialization error", err)
+	}
+
+	if err := waitForMempool(h, 2, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if relayed := h.Nodes[1].Mempool().Get(txHash); relayed == nil || !relayed.Vouts[len(relayed.Vouts)-1].IsDataOutput() {
+		t.Fatal("sendmany transaction with data was not relayed")
+	}
+}